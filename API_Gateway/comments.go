@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// censorComment отправляет комментарий в сервис цензурирования.
+// Возвращает false, если комментарий содержит запрещённые слова.
+func (api *API) censorComment(ctx context.Context, commentJSON []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, censorTimeout)
+	defer cancel()
+
+	req, err := api.censor.newRequest(ctx, http.MethodPost, "/censor", bytes.NewBuffer(commentJSON))
+	if err != nil {
+		return false, fmt.Errorf("Failed to create request to censor service: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("Failed to send request to censor service: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Если статус 400, то комментарий не прошел цензуру
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusBadRequest:
+		return false, nil
+	default:
+		return false, fmt.Errorf("Error from censor service: %s", resp.Status)
+	}
+}
+
+// proxyCommentError возвращает клиенту ошибку сервиса комментариев:
+// 4xx передаются как есть, остальные превращаются в 500
+func proxyCommentError(w http.ResponseWriter, resp *http.Response) {
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		body, _ := io.ReadAll(resp.Body)
+		http.Error(w, strings.TrimSpace(string(body)), resp.StatusCode)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Error from comment service: %s", resp.Status), http.StatusInternalServerError)
+}
+
+// fetchCommentsPage запрашивает первую страницу дерева комментариев к новости
+func (api *API) fetchCommentsPage(ctx context.Context, id string) (CommentsPage, error) {
+	var page CommentsPage
+
+	req, err := api.comments.newRequest(ctx, http.MethodGet, fmt.Sprintf("/comments/%s?view=tree", id), nil)
+	if err != nil {
+		return page, fmt.Errorf("Failed to fetch comments: %v", err)
+	}
+
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return page, fmt.Errorf("Failed to fetch comments: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return page, fmt.Errorf("Failed to fetch comments: %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&page.Comments); err != nil {
+		return page, fmt.Errorf("Error decoding comments response: %v", err)
+	}
+	page.Total, _ = strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	if next := resp.Header.Get("X-Next-Cursor"); next != "" {
+		page.Next = fmt.Sprintf("/news/%s/comments?view=tree&cursor=%s", id, url.QueryEscape(next))
+	}
+
+	return page, nil
+}
+
+// Параметры запроса страницы комментариев, которые пробрасываются в CommentService
+var commentListParams = []string{"view", "limit", "sort", "cursor"}
+
+// getComments возвращает страницу комментариев к новости. Общее количество и курсор
+// следующей страницы передаются в заголовках X-Total-Count и X-Next-Cursor.
+func (api *API) getComments(w http.ResponseWriter, r *http.Request) {
+	newsID := mux.Vars(r)["id"]
+	if _, err := strconv.Atoi(newsID); err != nil {
+		http.Error(w, "Invalid NewsID", http.StatusBadRequest)
+		return
+	}
+
+	query := url.Values{}
+	for _, name := range commentListParams {
+		if value := r.URL.Query().Get(name); value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), commentsTimeout)
+	defer cancel()
+	req, err := api.comments.newRequest(ctx, http.MethodGet, fmt.Sprintf("/comments/%s?%s", newsID, query.Encode()), nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch comments: %v", err), http.StatusInternalServerError)
+		return
+	}
+	resp, err := api.client.Do(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch comments: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		proxyCommentError(w, resp)
+		return
+	}
+
+	for _, header := range []string{"X-Total-Count", "X-Next-Cursor"} {
+		if value := resp.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, resp.Body)
+}
+
+// editComment проверяет новый текст в сервисе цензурирования и сохраняет его в сервисе комментариев
+func (api *API) editComment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	newsIDStr, commentIDStr := params["id"], params["commentID"]
+
+	newsID, err := strconv.Atoi(newsIDStr)
+	if err != nil {
+		http.Error(w, "Invalid NewsID", http.StatusBadRequest)
+		return
+	}
+	commentID, err := strconv.Atoi(commentIDStr)
+	if err != nil {
+		http.Error(w, "Invalid CommentID", http.StatusBadRequest)
+		return
+	}
+
+	var edit Comment
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	edit.ID = commentID
+	edit.NewsID = newsID
+
+	commentJSON, err := json.Marshal(edit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to marshal comment: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if approved, err := api.censorComment(r.Context(), commentJSON); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !approved {
+		http.Error(w, "Comment contains forbidden words", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), commentsTimeout)
+	defer cancel()
+	req, err := api.comments.newRequest(ctx, http.MethodPut, fmt.Sprintf("/comments/%d/%d", newsID, commentID), bytes.NewBuffer(commentJSON))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create request to comment service: %v", err), http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := api.client.Do(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to send request to comment service: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		proxyCommentError(w, resp)
+		return
+	}
+
+	var updated Comment
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		http.Error(w, fmt.Sprintf("Error decoding comment response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// deleteComment удаляет комментарий в сервисе комментариев и уменьшает счётчик комментариев новости
+func (api *API) deleteComment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	newsIDStr, commentIDStr := params["id"], params["commentID"]
+
+	if _, err := strconv.Atoi(newsIDStr); err != nil {
+		http.Error(w, "Invalid NewsID", http.StatusBadRequest)
+		return
+	}
+	if _, err := strconv.Atoi(commentIDStr); err != nil {
+		http.Error(w, "Invalid CommentID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), commentsTimeout)
+	defer cancel()
+	req, err := api.comments.newRequest(ctx, http.MethodDelete, fmt.Sprintf("/comments/%s/%s", newsIDStr, commentIDStr), nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to create request to comment service: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := api.client.Do(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to send request to comment service: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		proxyCommentError(w, resp)
+		return
+	}
+
+	if err := api.syncCommentsCount(r.Context(), newsIDStr); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to sync comments count", "news_id", newsIDStr, "error", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
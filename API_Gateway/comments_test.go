@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeleteCommentStalledCountSync(t *testing.T) {
+	news := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/news/1/comments_count/sync" {
+			t.Errorf("unexpected news request %s %s", r.Method, r.URL.Path)
+		}
+		// Микросервис новостей не отвечает, пока gateway не прервёт запрос
+		<-r.Context().Done()
+	})
+	comments := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	api := newTestAPI(t, news, comments)
+	api.newsInternalToken = "secret"
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	api.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/news/1/comments/2", nil))
+	elapsed := time.Since(start)
+
+	// Комментарий удалён, поэтому ответ успешный, даже если счётчик не обновился
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want 204; body %s", rec.Code, rec.Body)
+	}
+	if elapsed > syncTimeout+time.Second {
+		t.Errorf("response took %v, want at most about %v", elapsed, syncTimeout)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"common/config"
+	"common/logging"
+	"common/tracing"
+)
+
+// UpstreamConfig - адреса экземпляров одного микросервиса
+type UpstreamConfig struct {
+	URLs       []string `json:"urls" yaml:"urls"`               // базовые адреса, например http://localhost:8082
+	HealthPath string   `json:"health_path" yaml:"health_path"` // путь проверки доступности экземпляра
+}
+
+// Config - настройки API Gateway из файла config.yaml (допускается и JSON)
+// и переменных окружения.
+//
+// Переменные окружения имеют приоритет над файлом:
+//
+//	GATEWAY_CONFIG           - путь к файлу конфигурации
+//	GATEWAY_LISTEN_ADDR      - адрес HTTP-сервера, например :8080
+//	GATEWAY_LOG_LEVEL - уровень журнала: debug, info, warn или error
+//	GATEWAY_UPSTREAM_<NAME>  - адреса экземпляров через запятую, например
+//	                           GATEWAY_UPSTREAM_NEWS=http://news-1:8082,http://news-2:8082
+//	GATEWAY_HEALTH_INTERVAL  - период проверки экземпляров, например 10s
+//	GATEWAY_READ_TIMEOUT, GATEWAY_WRITE_TIMEOUT, GATEWAY_IDLE_TIMEOUT - таймауты HTTP-сервера
+//	GATEWAY_SHUTDOWN_TIMEOUT - время на завершение текущих запросов при остановке
+//	GATEWAY_NEWS_INTERNAL_TOKEN - токен внутренних маршрутов NewsService (NEWS_INTERNAL_TOKEN)
+//	GATEWAY_TRACING_EXPORTER - экспорт трассировки: none, stdout (спаны пишутся в stderr) или otlp
+//	GATEWAY_TRACING_ENDPOINT - адрес приёма спанов OTLP/HTTP, например http://localhost:4318/v1/traces
+//	GATEWAY_TRACING_SAMPLE_RATIO - доля трассируемых запросов от 0 до 1
+type Config struct {
+	Listen         string                    `json:"listen" yaml:"listen"`
+	LogLevel       string                    `json:"log_level" yaml:"log_level"`
+	Server         config.Server             `json:"server" yaml:"server"`
+	Upstreams      map[string]UpstreamConfig `json:"upstreams" yaml:"upstreams"`
+	HealthInterval config.Duration           `json:"health_interval" yaml:"health_interval"`
+	Tracing        tracing.Config            `json:"tracing" yaml:"tracing"`
+	// Токен для внутренних маршрутов NewsService, например синхронизации счётчика комментариев
+	NewsInternalToken string `json:"news_internal_token" yaml:"news_internal_token"`
+}
+
+const (
+	configPath            = "config.yaml"
+	defaultHealthPath     = "/readyz"
+	defaultHealthInterval = 10 * time.Second
+)
+
+// Микросервисы, к которым обращается API Gateway
+const (
+	upstreamNews     = "news"
+	upstreamComments = "comments"
+	upstreamCensor   = "censor"
+)
+
+var requiredUpstreams = []string{upstreamNews, upstreamComments, upstreamCensor}
+
+// loadConfig читает конфигурацию из файла и переменных окружения. Отсутствие файла
+// по умолчанию не является ошибкой: тогда микросервисы ищутся на localhost.
+func loadConfig() (Config, error) {
+	cfg := Config{
+		Listen:   ":8080",
+		LogLevel: "info",
+		Server:   config.DefaultServer(),
+		Upstreams: map[string]UpstreamConfig{
+			upstreamNews:     {URLs: []string{"http://localhost:8082"}},
+			upstreamComments: {URLs: []string{"http://localhost:8081"}},
+			upstreamCensor:   {URLs: []string{"http://localhost:8083"}},
+		},
+		HealthInterval: config.Duration(defaultHealthInterval),
+		Tracing:        tracing.DefaultConfig(),
+	}
+
+	if err := config.Load("GATEWAY_CONFIG", configPath, &cfg); err != nil {
+		return cfg, err
+	}
+
+	err := config.ApplyEnv(cfg.Server.Env("GATEWAY_"), cfg.Tracing.Env("GATEWAY_"), map[string]any{
+		"GATEWAY_LISTEN_ADDR":         &cfg.Listen,
+		"GATEWAY_LOG_LEVEL":           &cfg.LogLevel,
+		"GATEWAY_HEALTH_INTERVAL":     &cfg.HealthInterval,
+		"GATEWAY_NEWS_INTERNAL_TOKEN": &cfg.NewsInternalToken,
+	})
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		name, ok := strings.CutPrefix(key, "GATEWAY_UPSTREAM_")
+		if !ok || value == "" {
+			continue
+		}
+		name = strings.ToLower(name)
+		upstream := cfg.Upstreams[name]
+		upstream.URLs = strings.Split(value, ",")
+		cfg.Upstreams[name] = upstream
+	}
+
+	return cfg, cfg.validate()
+}
+
+// validate проверяет адреса микросервисов и заполняет значения по умолчанию
+func (cfg *Config) validate() error {
+	if cfg.Listen == "" {
+		return errors.New("listen address is required (listen or GATEWAY_LISTEN_ADDR)")
+	}
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		return fmt.Errorf("invalid log_level %q: must be debug, info, warn or error", cfg.LogLevel)
+	}
+
+	if err := config.Positive(cfg.Server.Durations(), map[string]config.Duration{
+		"health_interval": cfg.HealthInterval,
+	}); err != nil {
+		return err
+	}
+
+	if err := cfg.Tracing.Validate(); err != nil {
+		return err
+	}
+
+	for _, name := range requiredUpstreams {
+		if len(cfg.Upstreams[name].URLs) == 0 {
+			return fmt.Errorf("upstream %s has no urls", name)
+		}
+	}
+
+	for name, upstream := range cfg.Upstreams {
+		for i, raw := range upstream.URLs {
+			raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+			if err := config.CheckURL("upstream "+name+":", raw); err != nil {
+				return err
+			}
+			upstream.URLs[i] = raw
+		}
+		if upstream.HealthPath == "" {
+			upstream.HealthPath = defaultHealthPath
+		}
+		cfg.Upstreams[name] = upstream
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway.json")
+	data := `{
+		"log_level": "debug",
+		"health_interval": "3s",
+		"news_internal_token": "secret",
+		"upstreams": {
+			"news": {"urls": ["http://news:8082"], "health_path": "/healthz"},
+			"comments": {"urls": ["http://comments:8081"]},
+			"censor": {"urls": ["http://censor:8083"]}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GATEWAY_CONFIG", path)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if cfg.LogLevel != "debug" {
+		t.Errorf("log_level = %q, want debug", cfg.LogLevel)
+	}
+	if time.Duration(cfg.HealthInterval) != 3*time.Second {
+		t.Errorf("health_interval = %v, want 3s", time.Duration(cfg.HealthInterval))
+	}
+	if cfg.NewsInternalToken != "secret" {
+		t.Errorf("news_internal_token = %q, want secret", cfg.NewsInternalToken)
+	}
+	if got := cfg.Upstreams[upstreamNews].HealthPath; got != "/healthz" {
+		t.Errorf("news health_path = %q, want /healthz", got)
+	}
+}
+
+func TestLoadConfigUnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("log_level: info\nhealth_intervall: 3s\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GATEWAY_CONFIG", path)
+
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig: want error for unknown key health_intervall")
+	}
+}
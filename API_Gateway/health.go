@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// healthStatus - ответ /healthz и /readyz; в upstreams указано состояние микросервисов
+type healthStatus struct {
+	Status    string            `json:"status"`
+	Upstreams map[string]string `json:"upstreams,omitempty"`
+}
+
+func writeHealth(w http.ResponseWriter, status int, body healthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// healthz - проверка живости: процесс запущен и обрабатывает запросы
+func (api *API) healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthStatus{Status: "ok"})
+}
+
+// readyz - проверка готовности: у каждого микросервиса есть хотя бы один
+// экземпляр, прошедший последнюю проверку
+func (api *API) readyz(w http.ResponseWriter, r *http.Request) {
+	body := healthStatus{Status: "ok", Upstreams: map[string]string{}}
+	status := http.StatusOK
+	for _, u := range api.upstreams() {
+		if u.available() {
+			body.Upstreams[u.name] = "ok"
+			continue
+		}
+		body.Upstreams[u.name] = "unavailable"
+		body.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+	writeHealth(w, status, body)
+}
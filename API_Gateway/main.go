@@ -2,16 +2,28 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"common/logging"
+	"common/middleware"
+	"common/tracing"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
 type NewsFullDetailed struct {
@@ -19,32 +31,88 @@ type NewsFullDetailed struct {
 	Title     string    `json:"title"`
 	Author    string    `json:"author"`
 	Content   string    `json:"content"`
+	Source    string    `json:"source"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
-type NewsShortDetailed struct {
-	ID        int       `json:"id"`
-	Title     string    `json:"title"`
-	Author    string    `json:"author"`
-	CreatedAt time.Time `json:"created_at"`
+type Comment struct {
+	ID        int        `json:"id"`
+	NewsID    int        `json:"news_id"`
+	Author    string     `json:"author"`
+	Text      string     `json:"text"`
+	ParentID  int        `json:"parent_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	Deleted   bool       `json:"deleted"`
 }
 
-type Comment struct {
-	ID        int       `json:"id"`
-	NewsID    int       `json:"news_id"`
-	Author    string    `json:"author"`
-	Text      string    `json:"text"`
-	ParentID  int       `json:"parent_id"`
-	CreatedAt time.Time `json:"created_at"`
+// CommentNode - комментарий с вложенными ответами (view=tree в сервисе комментариев)
+type CommentNode struct {
+	Comment
+	Depth      int            `json:"depth"`
+	ReplyCount int            `json:"reply_count"`
+	Replies    []*CommentNode `json:"replies"`
+}
+
+// CommentsPage - страница комментариев верхнего уровня с ответами
+type CommentsPage struct {
+	Comments []*CommentNode
+	Total    int    // количество комментариев верхнего уровня
+	Next     string // ссылка на следующую страницу в API Gateway
+}
+
+// NewsDetailsResponse - ответ GET /news/{id}: новость и первая страница дерева
+// комментариев. Время передаётся в формате RFC 3339.
+//
+//	{
+//	  "news": {
+//	    "id": 1, "title": "...", "author": "...", "content": "...", "source": "habr.com",
+//	    "created_at": "2024-01-02T15:04:05Z"
+//	  },
+//	  "comments": [
+//	    {
+//	      "id": 7, "news_id": 1, "author": "...", "text": "...", "parent_id": 0,
+//	      "created_at": "2024-01-02T16:00:00Z", "edited_at": null, "deleted": false,
+//	      "depth": 0, "reply_count": 1,
+//	      "replies": [{"id": 8, "parent_id": 7, "depth": 1, "replies": [], ...}]
+//	    }
+//	  ],
+//	  "comments_total": 12,
+//	  "comments_next": "/news/1/comments?view=tree&cursor=..."
+//	}
+//
+// comments_total - количество комментариев верхнего уровня; comments_next равен null,
+// если других страниц нет. Если сервис комментариев не ответил, comments равен null,
+// а в массиве warnings перечислены недоступные данные.
+type NewsDetailsResponse struct {
+	News          NewsFullDetailed `json:"news"`
+	Comments      []*CommentNode   `json:"comments"`
+	CommentsTotal int              `json:"comments_total"`
+	CommentsNext  *string          `json:"comments_next"`
+	Warnings      []string         `json:"warnings,omitempty"`
 }
 
 type API struct {
-	r *mux.Router
+	r      *mux.Router
+	client *http.Client // клиент для запросов к микросервисам
+
+	// Микросервисы новостей, комментариев и цензурирования
+	news     *upstream
+	comments *upstream
+	censor   *upstream
+
+	newsInternalToken string // токен внутренних маршрутов NewsService
 }
 
-func NewAPI() *API {
+func NewAPI(cfg Config) *API {
 	api := &API{
-		r: mux.NewRouter(),
+		r:        mux.NewRouter(),
+		client:   &http.Client{Transport: newUpstreamTransport(), Timeout: upstreamTimeout},
+		news:     newUpstream(upstreamNews, cfg.Upstreams[upstreamNews]),
+		comments: newUpstream(upstreamComments, cfg.Upstreams[upstreamComments]),
+		censor:   newUpstream(upstreamCensor, cfg.Upstreams[upstreamCensor]),
+
+		newsInternalToken: cfg.NewsInternalToken,
 	}
 	api.endpoints()
 	return api
@@ -54,165 +122,260 @@ func (api *API) Router() *mux.Router {
 	return api.r
 }
 
+func (api *API) upstreams() []*upstream {
+	return []*upstream{api.news, api.comments, api.censor}
+}
+
 func (api *API) endpoints() {
+	api.r.HandleFunc("/healthz", api.healthz).Methods(http.MethodGet)
+	api.r.HandleFunc("/readyz", api.readyz).Methods(http.MethodGet)
+	api.r.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
 	api.r.HandleFunc("/news", api.getNews).Methods(http.MethodGet)
 	api.r.HandleFunc("/news/{id}", api.getSoloNews).Methods(http.MethodGet)
+	api.r.HandleFunc("/news/{id}/comments", api.getComments).Methods(http.MethodGet)
 	api.r.HandleFunc("/news/{id}/comments", api.addComment).Methods(http.MethodPost)
+	api.r.HandleFunc("/news/{id}/comments/{commentID}", api.editComment).Methods(http.MethodPut)
+	api.r.HandleFunc("/news/{id}/comments/{commentID}", api.deleteComment).Methods(http.MethodDelete)
 }
 
+// Параметры запроса списка новостей, которые пробрасываются в NewsService
+var newsListParams = []string{"page", "cursor", "limit", "sort", "order", "s", "lang", "author", "from", "to"}
+
 func (api *API) getNews(w http.ResponseWriter, r *http.Request) {
-	requestID := r.URL.Query().Get("request_id")
-	if requestID == "" {
-		requestID = generateRequestID()
+	// Параметры списка передаются в микросервис новостей как есть
+	query := url.Values{}
+	for _, name := range newsListParams {
+		if value := r.URL.Query().Get(name); value != "" {
+			query.Set(name, value)
+		}
 	}
-	page := r.URL.Query().Get("page")
-	s := r.URL.Query().Get("s")
+
+	ctx, cancel := context.WithTimeout(r.Context(), newsTimeout)
+	defer cancel()
 
 	// Создаем HTTP запрос к микросервису новостей
-	url := fmt.Sprintf("http://localhost:8082/news?request_id=%s&page=%s&s=%s", requestID, page, s) // Микросервис комментариев на порту 8082
+	req, err := api.news.newRequest(ctx, http.MethodGet, "/news?"+query.Encode(), nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch news: %v", err), http.StatusInternalServerError)
+		return
+	}
 
 	// Выполняем GET запрос к микросервису
-	resp, err := http.Get(url)
+	resp, err := api.client.Do(req)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to fetch comments: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to fetch news: %v", err), http.StatusInternalServerError)
 		return
 	}
 	defer resp.Body.Close()
 
+	// Ошибки в параметрах запроса возвращаем клиенту в исходном виде
+	if resp.StatusCode == http.StatusBadRequest {
+		w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		return
+	}
 	if resp.StatusCode != http.StatusOK {
-		http.Error(w, fmt.Sprintf("Failed to fetch comments: %s", resp.Status), resp.StatusCode)
+		http.Error(w, fmt.Sprintf("Failed to fetch news: %s", resp.Status), resp.StatusCode)
+		return
+	}
+
+	// Ответ разбирается только до уровня отдельных новостей: поля, о которых
+	// API Gateway не знает, передаются клиенту без изменений
+	var list map[string]json.RawMessage
+	var news []map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		http.Error(w, fmt.Sprintf("Error decoding news response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := json.Unmarshal(list["news"], &news); err != nil {
+		http.Error(w, fmt.Sprintf("Error decoding news response: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	// Количество комментариев запрашиваем одним запросом на всю страницу
+	ids := make([]int, len(news))
+	for i, item := range news {
+		if err := json.Unmarshal(item["id"], &ids[i]); err != nil {
+			http.Error(w, fmt.Sprintf("Error decoding news response: invalid id: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	counts, err := api.fetchCommentCounts(r.Context(), ids)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error reading response body: %v", err), http.StatusInternalServerError)
+		slog.WarnContext(r.Context(), "Failed to fetch comment counts", "error", err)
+	}
+	// comments_count равен null, если сервис комментариев недоступен
+	for i, item := range news {
+		item["comments_count"] = json.RawMessage("null")
+		if count, ok := counts[ids[i]]; ok {
+			item["comments_count"] = json.RawMessage(strconv.Itoa(count))
+		}
+	}
+	if list["news"], err = json.Marshal(news); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_, err = w.Write(bodyBytes) // Отправляем содержимое в ответ
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// fetchCommentCounts запрашивает в сервисе комментариев количество комментариев к новостям
+func (api *API) fetchCommentCounts(ctx context.Context, ids []int) (map[int]int, error) {
+	counts := make(map[int]int, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commentsTimeout)
+	defer cancel()
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+
+	req, err := api.comments.newRequest(ctx, http.MethodGet, "/comments/counts?news_id="+strings.Join(parts, ","), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := api.client.Do(req)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to write response: %v", err), http.StatusInternalServerError)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from comment service: %s", resp.Status)
+	}
+
+	var byID map[string]int
+	if err := json.NewDecoder(resp.Body).Decode(&byID); err != nil {
+		return nil, err
+	}
+	for key, count := range byID {
+		if id, err := strconv.Atoi(key); err == nil {
+			counts[id] = count
+		}
 	}
+
+	return counts, nil
 }
 
+// Таймауты запросов к микросервисам. upstreamTimeout - общий предел клиента
+// на случай, если для вызова не задан собственный таймаут.
+const (
+	newsTimeout     = 3 * time.Second
+	commentsTimeout = 2 * time.Second
+	censorTimeout   = 2 * time.Second
+	upstreamTimeout = 10 * time.Second
+)
+
+// Таймаут синхронизации счётчика комментариев после добавления или удаления комментария.
+// Комментарий к этому моменту уже сохранён, поэтому ответ клиенту не должен зависать
+// из-за медленного микросервиса новостей.
+const syncTimeout = 2 * time.Second
+
+var errNewsNotFound = errors.New("News not found")
+
+// getSoloNews собирает новость и комментарии к ней. Без новости ответ не имеет смысла,
+// поэтому её ошибки возвращаются клиенту; если недоступны комментарии, новость
+// возвращается с "comments": null и описанием проблемы в warnings.
 func (api *API) getSoloNews(w http.ResponseWriter, r *http.Request) {
 	param := mux.Vars(r)
 	id := param["id"]
-	requestID := r.URL.Query().Get("request_id")
-	if requestID == "" {
-		requestID = generateRequestID()
-	}
-
-	resultCh := make(chan interface{}, 2)
 
-	var wg sync.WaitGroup
+	var (
+		news        NewsFullDetailed
+		newsErr     error
+		page        CommentsPage
+		commentsErr error
+		wg          sync.WaitGroup
+	)
 	wg.Add(2)
 
 	// Горутина для запроса новости
 	go func() {
 		defer wg.Done()
-		url := fmt.Sprintf("http://localhost:8082/news/%s?request_id=%s", id, requestID)
-		resp, err := http.Get(url)
-		if err != nil {
-			resultCh <- fmt.Errorf("Failed to fetch news: %v", err)
-			return
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			resultCh <- fmt.Errorf("Failed to fetch news: %s", resp.Status)
-			return
-		}
-
-		var news NewsFullDetailed
-		if err := json.NewDecoder(resp.Body).Decode(&news); err != nil {
-			resultCh <- fmt.Errorf("Error decoding news response: %v", err)
-			return
-		}
-
-		resultCh <- news
+		ctx, cancel := context.WithTimeout(r.Context(), newsTimeout)
+		defer cancel()
+		news, newsErr = api.fetchNews(ctx, id)
 	}()
 
 	// Горутина для запроса комментариев
 	go func() {
 		defer wg.Done()
-		url := fmt.Sprintf("http://localhost:8081/comments/%s?request_id=%s", id, requestID)
-		resp, err := http.Get(url)
-		if err != nil {
-			resultCh <- fmt.Errorf("Failed to fetch comments: %v", err)
-			return
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			resultCh <- fmt.Errorf("Failed to fetch comments: %s", resp.Status)
-			return
-		}
-
-		var comments []Comment
-		if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
-			resultCh <- fmt.Errorf("Error decoding comments response: %v", err)
-			return
-		}
-
-		resultCh <- comments
+		ctx, cancel := context.WithTimeout(r.Context(), commentsTimeout)
+		defer cancel()
+		page, commentsErr = api.fetchCommentsPage(ctx, id)
 	}()
 
 	// Ожидаем завершения всех горутин
 	wg.Wait()
 
-	var newsData interface{}
-	var commentData interface{}
-
-	// Получаем данные и ошибки из канала
-	for i := 0; i < 2; i++ {
-		result := <-resultCh
-		switch data := result.(type) {
-		case error:
-			http.Error(w, data.Error(), http.StatusInternalServerError)
-			return
-		case NewsFullDetailed:
-			newsData = data
-		case []Comment:
-			commentData = data
-		}
+	if errors.Is(newsErr, errNewsNotFound) {
+		http.Error(w, newsErr.Error(), http.StatusNotFound)
+		return
 	}
-
-	var finalResponse string
-	if newsData != nil {
-		news := newsData.(NewsFullDetailed)
-		finalResponse = fmt.Sprintf("{\"news\": {\"id\": %d, \"title\": \"%s\", \"author\": \"%s\", \"created_at\": \"%s\"},",
-			news.ID, news.Title, news.Author, news.CreatedAt)
-	} else {
-		http.Error(w, "No news data found", http.StatusInternalServerError)
+	if newsErr != nil {
+		http.Error(w, newsErr.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if commentData != nil {
-		comments := commentData.([]Comment)
-		finalResponse += "\"comments\": ["
-		for i, comment := range comments {
-			finalResponse += fmt.Sprintf("{\"id\": %d, \"author\": \"%s\", \"content\": \"%s\", \"created_at\": \"%s\"}",
-				comment.ID, comment.Author, comment.Text, comment.CreatedAt)
-			if i < len(comments)-1 {
-				finalResponse += ","
-			}
-		}
-		finalResponse += "]}"
+	response := NewsDetailsResponse{News: news}
+	if commentsErr != nil {
+		slog.WarnContext(r.Context(), "Comments are unavailable", "news_id", id, "error", commentsErr)
+		response.Warnings = append(response.Warnings, "Comments are temporarily unavailable")
 	} else {
-		http.Error(w, "No comment data found", http.StatusInternalServerError)
-		return
+		// Первая страница дерева комментариев; остальные страницы доступны по comments_next
+		response.Comments = page.Comments
+		if response.Comments == nil {
+			response.Comments = []*CommentNode{}
+		}
+		response.CommentsTotal = page.Total
+		if page.Next != "" {
+			response.CommentsNext = &page.Next
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_, err := w.Write([]byte(finalResponse))
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// fetchNews запрашивает новость в микросервисе новостей
+func (api *API) fetchNews(ctx context.Context, id string) (NewsFullDetailed, error) {
+	var news NewsFullDetailed
+
+	req, err := api.news.newRequest(ctx, http.MethodGet, fmt.Sprintf("/news/%s", id), nil)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to write response: %v", err), http.StatusInternalServerError)
+		return news, fmt.Errorf("Failed to fetch news: %v", err)
 	}
+
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return news, fmt.Errorf("Failed to fetch news: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return news, errNewsNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return news, fmt.Errorf("Failed to fetch news: %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&news); err != nil {
+		return news, fmt.Errorf("Error decoding news response: %v", err)
+	}
+
+	return news, nil
 }
 
 func (api *API) addComment(w http.ResponseWriter, r *http.Request) {
@@ -233,43 +396,34 @@ func (api *API) addComment(w http.ResponseWriter, r *http.Request) {
 
 	newComment.NewsID = newsID
 
-	commentJSON, err := json.Marshal(newComment)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to marshal comment: %v", err), http.StatusInternalServerError)
+	// Проверяем, что новость существует, до обращения к сервису цензурирования
+	if status, err := api.checkNewsExists(r.Context(), newsIDStr); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to check news: %v", err), http.StatusInternalServerError)
 		return
-	}
-
-	// Создаем HTTP POST запрос к сервису цензурирования
-	censorURL := "http://localhost:8083/censor"
-	req, err := http.NewRequest(http.MethodPost, censorURL, bytes.NewBuffer(commentJSON))
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create request to censor service: %v", err), http.StatusInternalServerError)
+	} else if status == http.StatusNotFound {
+		http.Error(w, "News not found", http.StatusNotFound)
 		return
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	// Выполняем POST запрос в сервис цензурирования
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	commentJSON, err := json.Marshal(newComment)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to send request to censor service: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to marshal comment: %v", err), http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
 
-	// Если статус 400, то комментарий не прошел цензуру
-	if resp.StatusCode == http.StatusBadRequest {
-		http.Error(w, "Comment contains forbidden words", http.StatusBadRequest)
+	// Проверяем комментарий в сервисе цензурирования
+	if approved, err := api.censorComment(r.Context(), commentJSON); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
-	} else if resp.StatusCode != http.StatusOK {
-		http.Error(w, fmt.Sprintf("Error from censor service: %s", resp.Status), http.StatusInternalServerError)
+	} else if !approved {
+		http.Error(w, "Comment contains forbidden words", http.StatusBadRequest)
 		return
 	}
 
 	// Если цензура прошла успешно, отправляем запрос на создание комментария в сервис комментариев
-	url := fmt.Sprintf("http://localhost:8081/comments/%s", newsIDStr)
-	req, err = http.NewRequest(http.MethodPost, url, bytes.NewBuffer(commentJSON))
+	ctx, cancel := context.WithTimeout(r.Context(), commentsTimeout)
+	defer cancel()
+	req, err := api.comments.newRequest(ctx, http.MethodPost, fmt.Sprintf("/comments/%s", newsIDStr), bytes.NewBuffer(commentJSON))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to create request to comment service: %v", err), http.StatusInternalServerError)
 		return
@@ -278,7 +432,7 @@ func (api *API) addComment(w http.ResponseWriter, r *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
 
 	// Выполняем POST запрос
-	resp, err = client.Do(req)
+	resp, err := api.client.Do(req)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to send request to comment service: %v", err), http.StatusInternalServerError)
 		return
@@ -286,6 +440,11 @@ func (api *API) addComment(w http.ResponseWriter, r *http.Request) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusCreated {
+		// Обновляем счётчик комментариев новости, используемый для sort=comments
+		if err := api.syncCommentsCount(r.Context(), newsIDStr); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to sync comments count", "news_id", newsIDStr, "error", err)
+		}
+
 		// Отправляем успешный ответ
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
@@ -293,56 +452,109 @@ func (api *API) addComment(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
 		}
 	} else {
-		http.Error(w, fmt.Sprintf("Error from comment service: %s", resp.Status), http.StatusInternalServerError)
+		// Ошибки проверки комментария (например, неверный parent_id) возвращаем клиенту
+		proxyCommentError(w, resp)
 	}
 }
 
-func generateRequestID() string {
-	return fmt.Sprintf("%d", time.Now().UnixNano())
-}
-
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
+// checkNewsExists запрашивает новость в микросервисе новостей и возвращает
+// http.StatusOK или http.StatusNotFound
+func (api *API) checkNewsExists(ctx context.Context, newsID string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, newsTimeout)
+	defer cancel()
 
-// WriteHeader записывает HTTP-статус и сохраняет его в переменную
-func (rw *responseWriter) WriteHeader(statusCode int) {
-	rw.statusCode = statusCode
-	rw.ResponseWriter.WriteHeader(statusCode)
-}
+	req, err := api.news.newRequest(ctx, http.MethodGet, fmt.Sprintf("/news/%s", newsID), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
 
-// Write записывает тело ответа
-func (rw *responseWriter) Write(p []byte) (n int, err error) {
-	return rw.ResponseWriter.Write(p)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return resp.StatusCode, fmt.Errorf("unexpected status from news service: %s", resp.Status)
+	}
+	return resp.StatusCode, nil
 }
 
-func HeadersMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := r.URL.Query().Get("request_id")
-		if requestID == "" {
-			requestID = generateRequestID()
-		}
-
-		wrappedWriter := &responseWriter{ResponseWriter: w}
+// syncCommentsCount просит микросервис новостей пересчитать счётчик комментариев
+// новости по данным сервиса комментариев. Без токена внутренних маршрутов вызов
+// пропускается: счётчик исправит периодическая сверка в микросервисе новостей.
+// Комментарий уже сохранён, поэтому вызов не отменяется при отключении клиента,
+// но ограничен syncTimeout.
+func (api *API) syncCommentsCount(ctx context.Context, newsID string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), syncTimeout)
+	defer cancel()
+
+	if api.newsInternalToken == "" {
+		slog.DebugContext(ctx, "Skipping comments count sync: news internal token is not set", "news_id", newsID)
+		return nil
+	}
 
-		next.ServeHTTP(wrappedWriter, r)
+	req, err := api.news.newRequest(ctx, http.MethodPost, fmt.Sprintf("/news/%s/comments_count/sync", newsID), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+api.newsInternalToken)
 
-		log.Printf(
-			"Request ID: %s | Time: %s | IP: %s | Status: %d",
-			requestID,
-			time.Now().Format(time.RFC3339),
-			r.RemoteAddr,
-			wrappedWriter.statusCode,
-		)
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-	})
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from news service: %s", resp.Status)
+	}
+	return nil
 }
 
 func main() {
-	api := NewAPI()
-	api.Router().Use(HeadersMiddleware)
-	http.Handle("/", api.Router())
-	fmt.Println("Server started at http://localhost:8080/")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	cfg, err := loadConfig()
+	logging.Setup(cfg.LogLevel, "gateway")
+	if err != nil {
+		logging.Fatal("Failed to load config", "error", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, "gateway")
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
+
+	api := NewAPI(cfg)
+	for _, u := range api.upstreams() {
+		go u.runHealthChecks(ctx, time.Duration(cfg.HealthInterval))
+	}
+	middleware.Use(api.Router(), otelmux.Middleware("gateway"))
+	srv := &http.Server{
+		Addr:         cfg.Listen,
+		Handler:      api.Router(),
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout),
+	}
+	go func() {
+		slog.Info("Server started", "addr", cfg.Listen)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("Server failed", "error", err)
+		}
+	}()
+
+	// По SIGINT или SIGTERM сервер перестаёт принимать соединения и ждёт
+	// завершения текущих запросов не дольше shutdown_timeout
+	<-ctx.Done()
+	slog.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Graceful shutdown failed", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
 }
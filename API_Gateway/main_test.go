@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAPI создаёт API Gateway, который обращается к переданным фейковым микросервисам
+func newTestAPI(t *testing.T, news, comments http.Handler) *API {
+	t.Helper()
+	newsSrv := httptest.NewServer(news)
+	t.Cleanup(newsSrv.Close)
+	commentsSrv := httptest.NewServer(comments)
+	t.Cleanup(commentsSrv.Close)
+
+	return NewAPI(Config{Upstreams: map[string]UpstreamConfig{
+		upstreamNews:     {URLs: []string{newsSrv.URL}},
+		upstreamComments: {URLs: []string{commentsSrv.URL}},
+		upstreamCensor:   {URLs: []string{"http://127.0.0.1:0"}},
+	}})
+}
+
+func TestGetNewsKeepsUnknownFields(t *testing.T) {
+	news := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"news": [
+				{"id": 1, "title": "Первая", "tags": ["go"], "image": {"url": "https://example.com/1.png"}},
+				{"id": 2, "title": "Вторая"}
+			],
+			"pagination": {"totalPages": 1, "currentPage": 1, "pageSize": 15},
+			"facets": {"sources": 2}
+		}`))
+	})
+	comments := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("news_id"); got != "1,2" {
+			t.Errorf("news_id = %q, want 1,2", got)
+		}
+		w.Write([]byte(`{"1": 3}`))
+	})
+	api := newTestAPI(t, news, comments)
+
+	rec := httptest.NewRecorder()
+	api.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/news", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	var got struct {
+		News []struct {
+			ID            int               `json:"id"`
+			Tags          []string          `json:"tags"`
+			Image         map[string]string `json:"image"`
+			CommentsCount *int              `json:"comments_count"`
+		} `json:"news"`
+		Pagination map[string]int `json:"pagination"`
+		Facets     map[string]int `json:"facets"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v: %s", err, rec.Body)
+	}
+	if len(got.News) != 2 {
+		t.Fatalf("got %d news, want 2", len(got.News))
+	}
+	first, second := got.News[0], got.News[1]
+	if len(first.Tags) != 1 || first.Image["url"] != "https://example.com/1.png" {
+		t.Errorf("unknown news fields were lost: %s", rec.Body)
+	}
+	if first.CommentsCount == nil || *first.CommentsCount != 3 {
+		t.Errorf("news 1: comments_count = %v, want 3", first.CommentsCount)
+	}
+	if second.CommentsCount != nil {
+		t.Errorf("news 2: comments_count = %v, want null", *second.CommentsCount)
+	}
+	if got.Pagination["totalPages"] != 1 || got.Facets["sources"] != 2 {
+		t.Errorf("unknown top-level fields were lost: %s", rec.Body)
+	}
+}
+
+// Строки, которые ломали ответ, собранный через fmt.Sprintf
+var hostileStrings = map[string]string{
+	"script":  `</script><script>alert("xss")</script>`,
+	"html":    `<img src=x onerror=alert(1)> & <b>bold</b>`,
+	"quotes":  `"quoted" 'single' \ backslash \"escaped\"`,
+	"control": "line\nbreak\r\ttab\x00nul\x1b[31mred\x7f",
+	"unicode": "separators\u2028\u2029, emoji 🦫, RTL \u202etxet",
+	"json":    `{"injected": true}, "title": "fake"`,
+	"large":   strings.Repeat("Очень длинный текст новости. ", 40000),
+}
+
+func TestGetSoloNewsHostileStrings(t *testing.T) {
+	created := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	edited := created.Add(time.Hour)
+	news := NewsFullDetailed{
+		ID:        1,
+		Title:     hostileStrings["script"],
+		Author:    hostileStrings["quotes"],
+		Content:   hostileStrings["large"] + hostileStrings["control"],
+		Source:    hostileStrings["html"],
+		CreatedAt: created,
+	}
+	comments := []*CommentNode{{
+		Comment: Comment{ID: 7, NewsID: 1, Author: hostileStrings["json"], Text: hostileStrings["unicode"], CreatedAt: created},
+		Replies: []*CommentNode{{
+			Comment: Comment{ID: 8, NewsID: 1, Author: hostileStrings["control"], Text: hostileStrings["script"], ParentID: 7, CreatedAt: created, EditedAt: &edited},
+			Depth:   1,
+			Replies: []*CommentNode{},
+		}},
+		ReplyCount: 1,
+	}}
+	const cursor = `a&b=c"<>/ #`
+
+	api := newTestAPI(t,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(news)
+		}),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Total-Count", "1")
+			w.Header().Set("X-Next-Cursor", cursor)
+			json.NewEncoder(w).Encode(comments)
+		}),
+	)
+
+	rec := httptest.NewRecorder()
+	api.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/news/1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %.200s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	body := rec.Body.Bytes()
+	if !json.Valid(body) {
+		t.Fatalf("response is not valid JSON: %.200s", body)
+	}
+	// HTML и разделители строк экранируются, поэтому ответ безопасно встраивать в страницу
+	for _, raw := range []string{"<script>", "<img", "\u2028", "\x00"} {
+		if bytes.Contains(body, []byte(raw)) {
+			t.Errorf("response contains unescaped %q", raw)
+		}
+	}
+
+	var got NewsDetailsResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.News != news {
+		t.Errorf("news changed on the way through the gateway: title %q, author %q, source %q, content length %d",
+			got.News.Title, got.News.Author, got.News.Source, len(got.News.Content))
+	}
+	if len(got.Comments) != 1 || len(got.Comments[0].Replies) != 1 {
+		t.Fatalf("got comment tree %+v, want one comment with one reply", got.Comments)
+	}
+	root, reply := got.Comments[0], got.Comments[0].Replies[0]
+	if root.Author != comments[0].Author || root.Text != comments[0].Text {
+		t.Errorf("comment: author %q, text %q", root.Author, root.Text)
+	}
+	if reply.Author != hostileStrings["control"] || reply.Text != hostileStrings["script"] || reply.ParentID != 7 {
+		t.Errorf("reply: author %q, text %q, parent_id %d", reply.Author, reply.Text, reply.ParentID)
+	}
+	if reply.EditedAt == nil || !reply.EditedAt.Equal(edited) {
+		t.Errorf("reply: edited_at = %v, want %v", reply.EditedAt, edited)
+	}
+	if got.CommentsTotal != 1 || got.CommentsNext == nil {
+		t.Fatalf("comments_total = %d, comments_next = %v", got.CommentsTotal, got.CommentsNext)
+	}
+	next, err := url.Parse(*got.CommentsNext)
+	if err != nil || next.Path != "/news/1/comments" || next.Query().Get("cursor") != cursor {
+		t.Errorf("comments_next = %q does not carry the escaped cursor", *got.CommentsNext)
+	}
+}
+
+func TestGetSoloNewsUpstreamFailures(t *testing.T) {
+	newsOK := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 1, "title": "Новость"}`))
+	})
+	commentsOK := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database is down", http.StatusServiceUnavailable)
+	})
+	stalled := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	newsNotFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "News not found", http.StatusNotFound)
+	})
+
+	for _, tc := range []struct {
+		name           string
+		news, comments http.Handler
+		status         int
+		degraded       bool // новость без комментариев и с предупреждением
+	}{
+		{"comments 5xx", newsOK, failing, http.StatusOK, true},
+		{"comments timeout", newsOK, stalled, http.StatusOK, true},
+		{"news 404", newsNotFound, commentsOK, http.StatusNotFound, false},
+		{"news 5xx", failing, commentsOK, http.StatusInternalServerError, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newTestAPI(t, tc.news, tc.comments)
+			rec := httptest.NewRecorder()
+			api.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/news/1", nil))
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tc.status, rec.Body)
+			}
+			if !tc.degraded {
+				return
+			}
+
+			var got struct {
+				News     NewsFullDetailed `json:"news"`
+				Comments json.RawMessage  `json:"comments"`
+				Warnings []string         `json:"warnings"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON: %v: %s", err, rec.Body)
+			}
+			if got.News.ID != 1 {
+				t.Errorf("news id = %d, want 1", got.News.ID)
+			}
+			if string(got.Comments) != "null" {
+				t.Errorf("comments = %s, want null", got.Comments)
+			}
+			if len(got.Warnings) == 0 {
+				t.Error("warnings are empty, want a note about unavailable comments")
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Метрики запросов к микросервисам
+var (
+	upstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_requests_total",
+		Help: "Requests to upstream services by status; status is \"error\" if no response was received.",
+	}, []string{"upstream", "method", "status"})
+
+	upstreamRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "upstream_request_duration_seconds",
+		Help:    "Latency of requests to upstream services.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"upstream", "method"})
+
+	upstreamInstanceHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "upstream_instance_healthy",
+		Help: "1 if the upstream instance passed its last health probe, 0 otherwise.",
+	}, []string{"upstream", "instance"})
+)
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"common/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestTracePropagation проверяет, что запрос к Gateway и его запросы к микросервисам
+// попадают в одну трассу, а микросервисы получают traceparent исходящего спана
+func TestTracePropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	// traceparent, полученный каждым микросервисом
+	received := make(map[string]string)
+	news := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received[upstreamNews] = r.Header.Get("traceparent")
+		w.Write([]byte(`{"news": [{"id": 1, "title": "Первая"}], "pagination": {"totalPages": 1}}`))
+	})
+	comments := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received[upstreamComments] = r.Header.Get("traceparent")
+		w.Write([]byte(`{"1": 3}`))
+	})
+	api := newTestAPI(t, news, comments)
+	middleware.Use(api.Router(), otelmux.Middleware("gateway"))
+
+	rec := httptest.NewRecorder()
+	api.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/news", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	spans := exporter.GetSpans()
+	var handler *tracetest.SpanStub
+	outbound := make(map[string]tracetest.SpanStub) // ключ - имя микросервиса
+	for i, span := range spans {
+		switch span.SpanKind {
+		case trace.SpanKindServer:
+			handler = &spans[i]
+		case trace.SpanKindClient:
+			name, _, _ := strings.Cut(span.Name, " ")
+			outbound[name] = span
+		}
+	}
+	if handler == nil {
+		t.Fatalf("no handler span among %d spans", len(spans))
+	}
+	if handler.Name != "/news" {
+		t.Errorf("handler span name = %q, want /news", handler.Name)
+	}
+	traceID := handler.SpanContext.TraceID()
+
+	for _, name := range []string{upstreamNews, upstreamComments} {
+		span, ok := outbound[name]
+		if !ok {
+			t.Errorf("%s: no outbound span", name)
+			continue
+		}
+		if got := span.SpanContext.TraceID(); got != traceID {
+			t.Errorf("%s: outbound span trace ID %s, want %s", name, got, traceID)
+		}
+		if got := span.Parent.SpanID(); got != handler.SpanContext.SpanID() {
+			t.Errorf("%s: outbound span parent %s, want handler span %s", name, got, handler.SpanContext.SpanID())
+		}
+		// traceparent: версия-trace ID-span ID родителя-флаги
+		want := "00-" + traceID.String() + "-" + span.SpanContext.SpanID().String() + "-01"
+		if got := received[name]; got != want {
+			t.Errorf("%s: traceparent = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"common/requestid"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// instance - один экземпляр микросервиса
+type instance struct {
+	baseURL string
+	healthy atomic.Bool
+}
+
+// upstream - микросервис с одним или несколькими экземплярами. Запросы
+// распределяются по кругу между экземплярами, прошедшими последнюю проверку.
+type upstream struct {
+	name       string
+	healthPath string
+	instances  []*instance
+	next       atomic.Uint64
+}
+
+func newUpstream(name string, cfg UpstreamConfig) *upstream {
+	u := &upstream{name: name, healthPath: cfg.HealthPath}
+	for _, baseURL := range cfg.URLs {
+		inst := &instance{baseURL: baseURL}
+		// До первой проверки экземпляр считается доступным
+		inst.healthy.Store(true)
+		u.instances = append(u.instances, inst)
+	}
+	return u
+}
+
+// url возвращает адрес path на следующем доступном экземпляре
+func (u *upstream) url(path string) (string, error) {
+	n := uint64(len(u.instances))
+	start := u.next.Add(1) - 1
+	for i := uint64(0); i < n; i++ {
+		inst := u.instances[(start+i)%n]
+		if inst.healthy.Load() {
+			return inst.baseURL + path, nil
+		}
+	}
+	return "", fmt.Errorf("no healthy instances of %s service", u.name)
+}
+
+// available сообщает, есть ли у микросервиса доступные экземпляры
+func (u *upstream) available() bool {
+	for _, inst := range u.instances {
+		if inst.healthy.Load() {
+			return true
+		}
+	}
+	return false
+}
+
+type upstreamKey struct{}
+
+// newRequest создаёт запрос к path на следующем доступном экземпляре микросервиса
+// и передаёт в нём идентификатор исходного запроса из ctx
+func (u *upstream) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	target, err := u.url(path)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, upstreamKey{}, u.name), method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	requestid.Set(req)
+	return req, nil
+}
+
+// upstreamTransport записывает в журнал каждый запрос к микросервисам
+type upstreamTransport struct {
+	base http.RoundTripper
+}
+
+// newUpstreamTransport создаёт транспорт для запросов к микросервисам. Каждый запрос
+// становится спаном трассировки и передаёт её контекст в заголовке traceparent.
+func newUpstreamTransport() http.RoundTripper {
+	return otelhttp.NewTransport(
+		&upstreamTransport{base: http.DefaultTransport},
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			name, _ := req.Context().Value(upstreamKey{}).(string)
+			return name + " " + req.Method
+		}),
+	)
+}
+
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	latency := time.Since(start)
+
+	name, _ := req.Context().Value(upstreamKey{}).(string)
+	upstreamRequestDuration.WithLabelValues(name, req.Method).Observe(latency.Seconds())
+	attrs := []any{
+		"upstream", name,
+		"method", req.Method,
+		"path", req.URL.Path,
+		"latency_ms", float64(latency.Microseconds()) / 1000,
+	}
+	if err != nil {
+		upstreamRequests.WithLabelValues(name, req.Method, "error").Inc()
+		slog.WarnContext(req.Context(), "Upstream request failed", append(attrs, "error", err)...)
+		return nil, err
+	}
+	upstreamRequests.WithLabelValues(name, req.Method, strconv.Itoa(resp.StatusCode)).Inc()
+	slog.InfoContext(req.Context(), "Upstream request", append(attrs, "status", resp.StatusCode)...)
+	return resp, nil
+}
+
+// runHealthChecks периодически проверяет экземпляры до отмены ctx
+func (u *upstream) runHealthChecks(ctx context.Context, interval time.Duration) {
+	client := &http.Client{Timeout: interval / 2}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, inst := range u.instances {
+			u.probe(ctx, client, inst)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe проверяет экземпляр: он доступен, если ответил без ошибки 5xx
+func (u *upstream) probe(ctx context.Context, client *http.Client, inst *instance) {
+	healthy := false
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, inst.baseURL+u.healthPath, nil)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = client.Do(req); err == nil {
+			resp.Body.Close()
+			healthy = resp.StatusCode < http.StatusInternalServerError
+			if !healthy {
+				err = fmt.Errorf("status %s", resp.Status)
+			}
+		}
+	}
+
+	gauge := upstreamInstanceHealthy.WithLabelValues(u.name, inst.baseURL)
+	if healthy {
+		gauge.Set(1)
+	} else {
+		gauge.Set(0)
+	}
+
+	if inst.healthy.Swap(healthy) != healthy {
+		if healthy {
+			slog.Info("Upstream instance is healthy", "upstream", u.name, "instance", inst.baseURL)
+		} else {
+			slog.Warn("Upstream instance is unhealthy", "upstream", u.name, "instance", inst.baseURL, "error", err)
+		}
+	}
+}
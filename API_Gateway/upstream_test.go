@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUpstreamURLRotation(t *testing.T) {
+	u := newUpstream(upstreamNews, UpstreamConfig{URLs: []string{"http://a", "http://b", "http://c"}})
+
+	var got []string
+	for i := 0; i < 6; i++ {
+		target, err := u.url("/news")
+		if err != nil {
+			t.Fatalf("url: %v", err)
+		}
+		got = append(got, target)
+	}
+	want := []string{"http://a/news", "http://b/news", "http://c/news", "http://a/news", "http://b/news", "http://c/news"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("targets = %v, want %v", got, want)
+		}
+	}
+
+	// Недоступный экземпляр пропускается, остальные по-прежнему чередуются
+	u.instances[1].healthy.Store(false)
+	for i := 0; i < 4; i++ {
+		target, err := u.url("/news")
+		if err != nil {
+			t.Fatalf("url: %v", err)
+		}
+		if target == "http://b/news" {
+			t.Fatalf("unhealthy instance b was chosen")
+		}
+		got[i] = target
+	}
+	if got[0] == got[1] || got[2] == got[3] {
+		t.Errorf("healthy instances do not alternate: %v", got[:4])
+	}
+	if !u.available() {
+		t.Error("available() = false with two healthy instances")
+	}
+}
+
+func TestUpstreamURLAllDown(t *testing.T) {
+	u := newUpstream(upstreamComments, UpstreamConfig{URLs: []string{"http://a", "http://b"}})
+	for _, inst := range u.instances {
+		inst.healthy.Store(false)
+	}
+
+	if target, err := u.url("/comments"); err == nil {
+		t.Errorf("url = %q, want an error when all instances are down", target)
+	}
+	if u.available() {
+		t.Error("available() = true with no healthy instances")
+	}
+}
+
+func TestUpstreamProbe(t *testing.T) {
+	status := func(code int) string {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/healthz" {
+				t.Errorf("probe requested %s, want /healthz", r.URL.Path)
+			}
+			w.WriteHeader(code)
+		}))
+		t.Cleanup(srv.Close)
+		return srv.URL
+	}
+	// Адрес, на котором никто не слушает
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	for _, tc := range []struct {
+		name    string
+		url     string
+		healthy bool
+	}{
+		{"ok", status(http.StatusOK), true},
+		{"client error", status(http.StatusNotFound), true},
+		{"server error", status(http.StatusInternalServerError), false},
+		{"unavailable", status(http.StatusServiceUnavailable), false},
+		{"connection refused", closed.URL, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			u := newUpstream(upstreamCensor, UpstreamConfig{URLs: []string{tc.url}, HealthPath: "/healthz"})
+			inst := u.instances[0]
+			// Проверка должна изменить состояние в обе стороны
+			inst.healthy.Store(!tc.healthy)
+
+			u.probe(context.Background(), &http.Client{Timeout: time.Second}, inst)
+			if got := inst.healthy.Load(); got != tc.healthy {
+				t.Errorf("healthy = %v, want %v", got, tc.healthy)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"common/config"
+	"common/logging"
+	"common/tracing"
+)
+
+// Config - настройки сервиса цензурирования из файла config.json и переменных окружения.
+//
+// Переменные окружения имеют приоритет над файлом:
+//
+//	CENSOR_CONFIG      - путь к файлу конфигурации
+//	CENSOR_LISTEN_ADDR - адрес HTTP-сервера, например :8083
+//	CENSOR_LOG_LEVEL - уровень журнала: debug, info, warn или error
+//	CENSOR_READ_TIMEOUT, CENSOR_WRITE_TIMEOUT, CENSOR_IDLE_TIMEOUT - таймауты HTTP-сервера
+//	CENSOR_SHUTDOWN_TIMEOUT - время на завершение текущих запросов при остановке
+//	CENSOR_DICTIONARY_PATH - путь к JSON-файлу словаря запрещённых слов
+//	CENSOR_DICTIONARY_RELOAD_INTERVAL - период проверки изменений файла словаря, например 10s
+//	CENSOR_ADMIN_TOKEN - токен маршрутов правки словаря /censor/words, передаётся
+//	  в заголовке Authorization: Bearer <token>. Если токен не задан, маршруты
+//	  отвечают 403 и словарь меняется только правкой файла.
+//	CENSOR_TRACING_EXPORTER - экспорт трассировки: none, stdout (спаны пишутся в stderr) или otlp
+//	CENSOR_TRACING_ENDPOINT - адрес приёма спанов OTLP/HTTP, например http://localhost:4318/v1/traces
+//	CENSOR_TRACING_SAMPLE_RATIO - доля трассируемых запросов от 0 до 1
+type Config struct {
+	Listen     string           `json:"listen"`
+	LogLevel   string           `json:"log_level"`
+	Server     config.Server    `json:"server"`
+	Dictionary DictionaryConfig `json:"dictionary"`
+	Tracing    tracing.Config   `json:"tracing"`
+	AdminToken string           `json:"admin_token"` // токен для /censor/words; лучше задавать через CENSOR_ADMIN_TOKEN
+}
+
+// DictionaryConfig - словарь запрещённых слов. Словарь перечитывается по SIGHUP
+// и при изменении файла.
+type DictionaryConfig struct {
+	Path           string          `json:"path"`
+	ReloadInterval config.Duration `json:"reload_interval"` // период проверки изменений файла
+}
+
+const configPath = "config.json"
+
+// loadConfig читает конфигурацию из файла и переменных окружения и проверяет её.
+// Файл по умолчанию может отсутствовать, если всё задано переменными окружения.
+func loadConfig() (Config, error) {
+	cfg := Config{
+		Listen:   ":8083",
+		LogLevel: "info",
+		Server:   config.DefaultServer(),
+		Dictionary: DictionaryConfig{
+			Path:           "words.json",
+			ReloadInterval: config.Duration(10 * time.Second),
+		},
+		Tracing: tracing.DefaultConfig(),
+	}
+
+	if err := config.Load("CENSOR_CONFIG", configPath, &cfg); err != nil {
+		return cfg, err
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// applyEnv переопределяет настройки значениями из переменных окружения
+func (cfg *Config) applyEnv() error {
+	return config.ApplyEnv(cfg.Server.Env("CENSOR_"), cfg.Tracing.Env("CENSOR_"), map[string]any{
+		"CENSOR_LISTEN_ADDR":                &cfg.Listen,
+		"CENSOR_LOG_LEVEL":                  &cfg.LogLevel,
+		"CENSOR_DICTIONARY_PATH":            &cfg.Dictionary.Path,
+		"CENSOR_DICTIONARY_RELOAD_INTERVAL": &cfg.Dictionary.ReloadInterval,
+		"CENSOR_ADMIN_TOKEN":                &cfg.AdminToken,
+	})
+}
+
+func (cfg *Config) validate() error {
+	if cfg.Listen == "" {
+		return errors.New("listen address is required (listen or CENSOR_LISTEN_ADDR)")
+	}
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		return fmt.Errorf("invalid log_level %q: must be debug, info, warn or error", cfg.LogLevel)
+	}
+	if cfg.Dictionary.Path == "" {
+		return errors.New("dictionary path is required (dictionary.path or CENSOR_DICTIONARY_PATH)")
+	}
+
+	if err := config.Positive(cfg.Server.Durations(), map[string]config.Duration{
+		"dictionary.reload_interval": cfg.Dictionary.ReloadInterval,
+	}); err != nil {
+		return err
+	}
+
+	if err := cfg.Tracing.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode"
+)
+
+// Word - запрещённое слово словаря. Слово с * на конце запрещает все слова,
+// которые с него начинаются. Слово с allow - исключение: оно не считается
+// запрещённым, даже если подходит под другое слово словаря.
+type Word struct {
+	Word     string `json:"word"`
+	Category string `json:"category"` // например spam, insult, profanity
+	Severity int    `json:"severity"` // от 1 (низкая) до 3 (высокая)
+	Allow    bool   `json:"allow,omitempty"`
+}
+
+const (
+	defaultCategory = "general"
+	minSeverity     = 1
+	maxSeverity     = 3
+)
+
+// Слова, которые действуют, пока файл словаря не создан, например первой
+// правкой через /censor/words
+var defaultWords = []Word{
+	{Word: "qwerty", Category: defaultCategory, Severity: minSeverity},
+	{Word: "йцукен", Category: defaultCategory, Severity: minSeverity},
+	{Word: "zxvbnm", Category: defaultCategory, Severity: minSeverity},
+}
+
+// validate нормализует слово так же, как текст комментария, и заполняет значения по умолчанию
+func (w *Word) validate() error {
+	w.Word = normalizeText(strings.TrimSpace(w.Word))
+	w.Category = strings.TrimSpace(w.Category)
+	if w.Word == "" {
+		return errors.New("word is required")
+	}
+	if strings.IndexFunc(w.Word, unicode.IsSpace) >= 0 {
+		return errors.New("word must not contain spaces")
+	}
+	if word, _ := strings.CutSuffix(w.Word, "*"); strings.Contains(word, "*") || letters(word) == "" {
+		return errors.New("word must contain letters and may only end with *")
+	}
+	if w.Category == "" {
+		w.Category = defaultCategory
+	}
+	if w.Severity == 0 {
+		w.Severity = minSeverity
+	}
+	if w.Severity < minSeverity || w.Severity > maxSeverity {
+		return fmt.Errorf("severity must be between %d and %d", minSeverity, maxSeverity)
+	}
+	return nil
+}
+
+// Dictionary - словарь запрещённых слов из JSON-файла. Словарь перечитывается
+// по SIGHUP и при изменении файла, правки через /censor/words записываются в файл.
+type Dictionary struct {
+	path string
+
+	mu      sync.RWMutex
+	words   map[string]Word // ключ - нормализованное слово
+	matcher *matcher
+	modTime time.Time // время изменения файла при последней загрузке
+}
+
+// loadDictionary читает словарь из файла. Если файла ещё нет, используются
+// слова по умолчанию.
+func loadDictionary(path string) (*Dictionary, error) {
+	d := &Dictionary{path: path}
+	err := d.Reload()
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Dictionary file not found, using default words", "path", path)
+		words := make(map[string]Word, len(defaultWords))
+		for _, w := range defaultWords {
+			words[w.Word] = w
+		}
+		// Словарь ещё не доступен другим горутинам, поэтому d.mu не нужна
+		d.set(words, time.Time{})
+		return d, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Reload перечитывает файл словаря. При ошибке остаётся прежний словарь.
+// Файл читается под d.mu, чтобы одновременная правка через Put или Delete
+// не была затёрта прочитанным до неё содержимым.
+func (d *Dictionary) Reload() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		return err
+	}
+
+	var list []Word
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("invalid dictionary %s: %v", d.path, err)
+	}
+	words := make(map[string]Word, len(list))
+	for i := range list {
+		if err := list[i].validate(); err != nil {
+			return fmt.Errorf("invalid dictionary %s: entry %d: %v", d.path, i, err)
+		}
+		words[list[i].Word] = list[i]
+	}
+
+	d.set(words, info.ModTime())
+	return nil
+}
+
+// set делает words текущим словарём. Вызывается под d.mu.
+func (d *Dictionary) set(words map[string]Word, modTime time.Time) {
+	d.words = words
+	d.matcher = newMatcher(words)
+	d.modTime = modTime
+	dictionaryWords.Set(float64(len(words)))
+}
+
+// Len возвращает количество слов в словаре
+func (d *Dictionary) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.words)
+}
+
+// Match ищет в тексте запрещённые слова и возвращает самое строгое из найденных
+func (d *Dictionary) Match(text string) (Word, bool) {
+	d.mu.RLock()
+	m := d.matcher
+	d.mu.RUnlock()
+	return m.match(text)
+}
+
+// List возвращает слова по алфавиту; если category не пустая, только из этой категории
+func (d *Dictionary) List(category string) []Word {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return sortedWords(d.words, category)
+}
+
+func sortedWords(words map[string]Word, category string) []Word {
+	list := make([]Word, 0, len(words))
+	for _, w := range words {
+		if category == "" || w.Category == category {
+			list = append(list, w)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Word < list[j].Word })
+	return list
+}
+
+// Put добавляет слово или заменяет категорию и строгость существующего.
+// Возвращает true, если слова в словаре не было.
+func (d *Dictionary) Put(w Word) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, exists := d.words[w.Word]
+	words := make(map[string]Word, len(d.words)+1)
+	for k, v := range d.words {
+		words[k] = v
+	}
+	words[w.Word] = w
+
+	if err := d.save(words); err != nil {
+		return false, err
+	}
+	return !exists, nil
+}
+
+// Delete удаляет слово из словаря. Возвращает false, если слова в словаре не было.
+func (d *Dictionary) Delete(word string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, exists := d.words[word]; !exists {
+		return false, nil
+	}
+	words := make(map[string]Word, len(d.words))
+	for k, v := range d.words {
+		if k != word {
+			words[k] = v
+		}
+	}
+
+	if err := d.save(words); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// save записывает словарь в файл и делает его текущим. Файл сначала пишется
+// во временный и затем переименовывается, чтобы при перечитывании не попасть
+// на наполовину записанный файл. Вызывается под d.mu.
+func (d *Dictionary) save(words map[string]Word) error {
+	data, err := json.MarshalIndent(sortedWords(words, ""), "", "    ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), filepath.Base(d.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), d.path); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return err
+	}
+	d.set(words, info.ModTime())
+	return nil
+}
+
+// Watch перечитывает словарь по сигналу SIGHUP и при изменении файла,
+// которое проверяется раз в interval, до отмены ctx
+func (d *Dictionary) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Время изменения файла, который не удалось загрузить: повторная попытка
+	// делается только после следующего изменения, чтобы не засорять журнал
+	var failed time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			d.reload("signal")
+		case <-ticker.C:
+			if modTime, ok := d.changed(); ok && !modTime.Equal(failed) {
+				if !d.reload("file change") {
+					failed = modTime
+				}
+			}
+		}
+	}
+}
+
+// changed сообщает, изменился ли файл словаря после последней загрузки.
+// Если файла нет, словарь остаётся прежним.
+func (d *Dictionary) changed() (time.Time, bool) {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return info.ModTime(), !info.ModTime().Equal(d.modTime)
+}
+
+func (d *Dictionary) reload(reason string) bool {
+	if err := d.Reload(); err != nil {
+		dictionaryReloads.WithLabelValues("error").Inc()
+		slog.Error("Failed to reload dictionary", "path", d.path, "reason", reason, "error", err)
+		return false
+	}
+	dictionaryReloads.WithLabelValues("ok").Inc()
+	slog.Info("Dictionary reloaded", "path", d.path, "reason", reason, "words", d.Len())
+	return true
+}
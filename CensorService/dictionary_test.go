@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeWords записывает словарь в файл и сдвигает время изменения, чтобы
+// изменение было заметно даже при грубой точности времени файловой системы
+func writeWords(t *testing.T, path string, modTime time.Time, words ...Word) {
+	t.Helper()
+	data, err := json.Marshal(words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func matched(d *Dictionary, text string) string {
+	w, _ := d.Match(text)
+	return w.Word
+}
+
+func TestDictionaryDefaults(t *testing.T) {
+	d, err := loadDictionary(filepath.Join(t.TempDir(), "words.json"))
+	if err != nil {
+		t.Fatalf("loadDictionary: %v", err)
+	}
+	if d.Len() != len(defaultWords) {
+		t.Errorf("Len = %d, want %d default words", d.Len(), len(defaultWords))
+	}
+	if got := matched(d, "qwerty"); got != "qwerty" {
+		t.Errorf("Match(qwerty) = %q, want qwerty", got)
+	}
+}
+
+func TestDictionaryReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.json")
+	now := time.Now()
+	writeWords(t, path, now, Word{Word: "spam", Severity: 1})
+
+	d, err := loadDictionary(path)
+	if err != nil {
+		t.Fatalf("loadDictionary: %v", err)
+	}
+	if got := matched(d, "buy spam"); got != "spam" {
+		t.Fatalf("Match = %q, want spam", got)
+	}
+
+	writeWords(t, path, now.Add(time.Second), Word{Word: "scam", Category: "fraud", Severity: 3})
+	if err := d.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := matched(d, "buy spam"); got != "" {
+		t.Errorf("Match after reload = %q, want no match", got)
+	}
+	if got := d.List(""); !reflect.DeepEqual(got, []Word{{Word: "scam", Category: "fraud", Severity: 3}}) {
+		t.Errorf("List after reload = %+v", got)
+	}
+
+	// Файл с ошибкой не загружается, остаётся прежний словарь
+	for name, data := range map[string]string{
+		"bad json":     `[{"word": "scam"`,
+		"bad word":     `[{"word": "two words"}]`,
+		"bad severity": `[{"word": "scam", "severity": 9}]`,
+	} {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Reload(); err == nil {
+			t.Errorf("%s: Reload succeeded, want error", name)
+		}
+		if got := matched(d, "a scam"); got != "scam" {
+			t.Errorf("%s: Match = %q, want the previous dictionary", name, got)
+		}
+	}
+}
+
+func TestDictionaryPutDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.json")
+	writeWords(t, path, time.Now(), Word{Word: "spam", Category: defaultCategory, Severity: 1})
+	d, err := loadDictionary(path)
+	if err != nil {
+		t.Fatalf("loadDictionary: %v", err)
+	}
+
+	scam := Word{Word: "scam", Category: "fraud", Severity: 2}
+	if created, err := d.Put(scam); err != nil || !created {
+		t.Fatalf("Put(new) = %v, %v; want true", created, err)
+	}
+	scam.Severity = 3
+	if created, err := d.Put(scam); err != nil || created {
+		t.Fatalf("Put(existing) = %v, %v; want false", created, err)
+	}
+	if got, _ := d.Match("a scam"); got != scam {
+		t.Errorf("Match = %+v, want updated %+v", got, scam)
+	}
+
+	if deleted, err := d.Delete("spam"); err != nil || !deleted {
+		t.Fatalf("Delete(spam) = %v, %v; want true", deleted, err)
+	}
+	if deleted, err := d.Delete("spam"); err != nil || deleted {
+		t.Fatalf("Delete(spam) again = %v, %v; want false", deleted, err)
+	}
+	if got := matched(d, "buy spam"); got != "" {
+		t.Errorf("Match after delete = %q, want no match", got)
+	}
+
+	// Правки записаны в файл
+	saved, err := loadDictionary(path)
+	if err != nil {
+		t.Fatalf("loadDictionary: %v", err)
+	}
+	if got := saved.List(""); !reflect.DeepEqual(got, []Word{scam}) {
+		t.Errorf("saved dictionary = %+v, want [%+v]", got, scam)
+	}
+	// Записанный файл не считается изменённым и не перечитывается
+	if _, changed := d.changed(); changed {
+		t.Error("file written by Put is reported as changed")
+	}
+}
+
+func TestDictionaryWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.json")
+	now := time.Now()
+	writeWords(t, path, now, Word{Word: "spam", Severity: 1})
+	d, err := loadDictionary(path)
+	if err != nil {
+		t.Fatalf("loadDictionary: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Watch(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	writeWords(t, path, now.Add(time.Second), Word{Word: "scam", Severity: 1})
+	deadline := time.Now().Add(2 * time.Second)
+	for matched(d, "a scam") != "scam" {
+		if time.Now().After(deadline) {
+			t.Fatal("dictionary was not reloaded after the file changed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := matched(d, "buy spam"); got != "" {
+		t.Errorf("Match = %q, want the old word to be gone", got)
+	}
+}
+
+// Перечитывание файла одновременно с правками не возвращает словарь,
+// прочитанный до правки
+func TestDictionaryReloadDuringPut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.json")
+	writeWords(t, path, time.Now(), Word{Word: "spam", Category: defaultCategory, Severity: 1})
+	d, err := loadDictionary(path)
+	if err != nil {
+		t.Fatalf("loadDictionary: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if err := d.Reload(); err != nil {
+					t.Errorf("Reload: %v", err)
+					return
+				}
+			}
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		w := Word{Word: fmt.Sprintf("word%c%c", 'a'+i%26, 'a'+i/26), Category: defaultCategory, Severity: 1}
+		if _, err := d.Put(w); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	saved, err := loadDictionary(path)
+	if err != nil {
+		t.Fatalf("loadDictionary: %v", err)
+	}
+	if got, want := d.Len(), saved.Len(); got != want || want != 51 {
+		t.Errorf("Len = %d, file has %d words, want 51", got, want)
+	}
+}
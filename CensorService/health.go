@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// healthStatus - ответ /healthz и /readyz
+type healthStatus struct {
+	Status string `json:"status"`
+}
+
+func writeHealth(w http.ResponseWriter, status int, body healthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// healthz - проверка живости: процесс запущен и обрабатывает запросы
+func (api *API) healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthStatus{Status: "ok"})
+}
+
+// readyz - проверка готовности. Внешних зависимостей у сервиса нет,
+// поэтому он готов, как только начал обрабатывать запросы.
+func (api *API) readyz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthStatus{Status: "ok"})
+}
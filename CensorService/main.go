@@ -1,18 +1,24 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
-	"strings"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"common/logging"
+	"common/middleware"
+	"common/tracing"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
-var forbiddenWords = []string{"qwerty", "йцукен", "zxvbnm"}
-
 type Comment struct {
 	ID        int       `json:"id"`
 	NewsID    int       `json:"news_id"`
@@ -23,12 +29,16 @@ type Comment struct {
 }
 
 type API struct {
-	r *mux.Router // маршрутизатор запросов
+	r          *mux.Router // маршрутизатор запросов
+	dict       *Dictionary // словарь запрещённых слов
+	adminToken string      // токен маршрутов правки словаря
 }
 
-func NewAPI() *API {
+func NewAPI(dict *Dictionary, adminToken string) *API {
 	api := &API{
-		r: mux.NewRouter(),
+		r:          mux.NewRouter(),
+		dict:       dict,
+		adminToken: adminToken,
 	}
 	api.endpoints() // Настройка маршрутов
 	return api
@@ -40,7 +50,14 @@ func (api *API) Router() *mux.Router {
 
 func (api *API) endpoints() {
 	// Обработчики для различных маршрутов
+	api.r.HandleFunc("/healthz", api.healthz).Methods(http.MethodGet)
+	api.r.HandleFunc("/readyz", api.readyz).Methods(http.MethodGet)
+	api.r.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
 	api.r.HandleFunc("/censor", api.censorComment).Methods(http.MethodPost)
+	// Словарь могут просматривать и править только модераторы с токеном администратора
+	api.r.HandleFunc("/censor/words", middleware.RequireToken(api.adminToken, api.getWords)).Methods(http.MethodGet)
+	api.r.HandleFunc("/censor/words", middleware.RequireToken(api.adminToken, api.addWord)).Methods(http.MethodPost)
+	api.r.HandleFunc("/censor/words/{word}", middleware.RequireToken(api.adminToken, api.deleteWord)).Methods(http.MethodDelete)
 }
 
 func (api *API) censorComment(w http.ResponseWriter, r *http.Request) {
@@ -53,71 +70,72 @@ func (api *API) censorComment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Проверка на наличие запрещенных слов
-	if containsForbiddenWords(comment.Text) {
+	if word, found := api.dict.Match(comment.Text); found {
+		censorVerdicts.WithLabelValues("rejected").Inc()
+		slog.InfoContext(r.Context(), "Comment rejected",
+			"comment_id", comment.ID,
+			"category", word.Category,
+			"severity", word.Severity,
+		)
 		http.Error(w, "Comment contains forbidden words", http.StatusBadRequest)
 		return
 	}
+	censorVerdicts.WithLabelValues("approved").Inc()
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Comment approved"))
 }
 
-func containsForbiddenWords(text string) bool {
-	for _, word := range forbiddenWords {
-		if strings.Contains(strings.ToLower(text), word) {
-			return true
-		}
+func main() {
+	cfg, err := loadConfig()
+	logging.Setup(cfg.LogLevel, "censor")
+	if err != nil {
+		logging.Fatal("Failed to load config", "error", err)
 	}
-	return false
-}
-
-func generateRequestID() string {
-	return fmt.Sprintf("%d", time.Now().UnixNano())
-}
 
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-// WriteHeader записывает HTTP-статус и сохраняет его в переменную
-func (rw *responseWriter) WriteHeader(statusCode int) {
-	rw.statusCode = statusCode
-	rw.ResponseWriter.WriteHeader(statusCode)
-}
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, "censor")
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
 
-// Write записывает тело ответа
-func (rw *responseWriter) Write(p []byte) (n int, err error) {
-	return rw.ResponseWriter.Write(p)
-}
+	dict, err := loadDictionary(cfg.Dictionary.Path)
+	if err != nil {
+		logging.Fatal("Failed to load dictionary", "error", err)
+	}
+	go dict.Watch(ctx, time.Duration(cfg.Dictionary.ReloadInterval))
 
-func HeadersMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := r.URL.Query().Get("request_id")
-		if requestID == "" {
-			requestID = generateRequestID()
+	if cfg.AdminToken == "" {
+		slog.Warn("Admin token is not set, /censor/words is disabled")
+	}
+	api := NewAPI(dict, cfg.AdminToken)
+	middleware.Use(api.Router(), otelmux.Middleware("censor"))
+	srv := &http.Server{
+		Addr:         cfg.Listen,
+		Handler:      api.Router(),
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout),
+	}
+	go func() {
+		slog.Info("Server started", "addr", cfg.Listen)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("Server failed", "error", err)
 		}
-
-		wrappedWriter := &responseWriter{ResponseWriter: w}
-
-		next.ServeHTTP(wrappedWriter, r)
-
-		log.Printf(
-			"Request ID: %s | Time: %s | IP: %s | Status: %d",
-			requestID,
-			time.Now().Format(time.RFC3339),
-			r.RemoteAddr,
-			wrappedWriter.statusCode,
-		)
-
-	})
-}
-
-func main() {
-
-	api := NewAPI()
-	api.Router().Use(HeadersMiddleware)
-	http.Handle("/", api.Router())
-	fmt.Println("Server started at http://localhost:8083/")
-	log.Fatal(http.ListenAndServe(":8083", api.r))
+	}()
+
+	// По SIGINT или SIGTERM сервер перестаёт принимать соединения и ждёт
+	// завершения текущих запросов не дольше shutdown_timeout
+	<-ctx.Done()
+	slog.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Graceful shutdown failed", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
 }
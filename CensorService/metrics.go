@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// censorVerdicts - результаты проверки комментариев
+var censorVerdicts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "censor_verdicts_total",
+	Help: "Censor verdicts by result: approved or rejected.",
+}, []string{"verdict"})
+
+// Метрики словаря запрещённых слов
+var (
+	dictionaryWords = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "censor_dictionary_words",
+		Help: "Number of words in the forbidden word dictionary.",
+	})
+
+	dictionaryReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "censor_dictionary_reloads_total",
+		Help: "Dictionary reloads by result: ok or error.",
+	}, []string{"result"})
+)
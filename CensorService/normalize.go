@@ -0,0 +1,249 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Буквы, которые в нижнем регистре неотличимы от латинских. Такие буквы
+// заменяются только в словах, где смешаны алфавиты: в "qwеrty" с кириллической е
+// это подмена, а русское "сор" - обычное слово, и со словом "cop" оно не совпадает.
+var toLatin = map[rune]rune{
+	// Кириллица
+	'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c', 'у': 'y', 'х': 'x',
+	'і': 'i', 'ј': 'j', 'ѕ': 's',
+	// Греческий алфавит
+	'α': 'a', 'ι': 'i', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'χ': 'x',
+}
+
+// toCyrillic - обратная замена для русских слов с латинскими буквами: "йцykeн"
+var toCyrillic = map[rune]rune{
+	'a': 'а', 'e': 'е', 'o': 'о', 'p': 'р', 'c': 'с', 'y': 'у', 'x': 'х', 'i': 'і',
+	'α': 'а', 'ο': 'о', 'ρ': 'р', 'χ': 'х',
+}
+
+// leetRunes - цифры и знаки leetspeak. Замена применяется только в словах,
+// в которых есть буквы, поэтому число "455" не превращается в "ass". Знаки
+// заменяются, только если за ними есть буква или цифра: "sh!t" и "$pam" - подмена,
+// а "!" в конце "naz!" - знак препинания.
+var leetRunes = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't',
+	'@': 'a', '$': 's', '!': 'i', '|': 'i',
+}
+
+// normalizeText приводит текст к форме NFKC и нижнему регистру: так полноширинные
+// и стилизованные буквы (ｑ, 𝐪) становятся обычными
+func normalizeText(text string) string {
+	return strings.ToLower(norm.NFKC.String(text))
+}
+
+// letters заменяет знаки leetspeak по leetRunes и отбрасывает всё, что не является
+// буквой, в том числе вставленные внутрь слова знаки препинания
+func letters(word string) string {
+	if !strings.ContainsFunc(word, unicode.IsLetter) {
+		return ""
+	}
+	// Знаки leetspeak после последней буквы или цифры не заменяются
+	end := strings.LastIndexFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+
+	var b strings.Builder
+	for i, r := range word {
+		if folded, ok := leetRunes[r]; ok && (unicode.IsDigit(r) || i < end) {
+			r = folded
+		}
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// variants возвращает формы слова для сравнения со словарём: само слово, а если
+// в нём смешаны алфавиты, ещё и его латинское и кириллическое прочтения
+func variants(word string) []string {
+	if !mixedScripts(word) {
+		return []string{word}
+	}
+	return []string{word, replaceRunes(word, toLatin), replaceRunes(word, toCyrillic)}
+}
+
+func mixedScripts(word string) bool {
+	var latin, cyrillic, greek bool
+	for _, r := range word {
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin = true
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic = true
+		case unicode.Is(unicode.Greek, r):
+			greek = true
+		}
+	}
+	n := 0
+	for _, used := range []bool{latin, cyrillic, greek} {
+		if used {
+			n++
+		}
+	}
+	return n > 1
+}
+
+func replaceRunes(word string, table map[rune]rune) string {
+	return strings.Map(func(r rune) rune {
+		if replaced, ok := table[r]; ok {
+			return replaced
+		}
+		return r
+	}, word)
+}
+
+// tokens разбивает текст на слова для сравнения со словарём. Кроме слов, разделённых
+// пробелами и знаками препинания, проверяются слова без вставленных внутрь знаков
+// ("q.w.e.r.t.y") и слова, набранные по одной букве через пробел ("q w e r t y").
+// Для слов со смешанными алфавитами возвращаются все их прочтения из variants.
+func tokens(text string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	add := func(token string) {
+		for _, v := range variants(token) {
+			if v != "" && !seen[v] {
+				seen[v] = true
+				result = append(result, v)
+			}
+		}
+	}
+
+	var single strings.Builder // подряд идущие однобуквенные слова
+	flush := func() {
+		if utf8.RuneCountInString(single.String()) > 1 {
+			add(single.String())
+		}
+		single.Reset()
+	}
+
+	for _, chunk := range strings.Fields(normalizeText(text)) {
+		joined := letters(chunk)
+		add(joined)
+
+		parts := strings.FieldsFunc(chunk, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, part := range parts {
+			add(letters(part))
+		}
+
+		if utf8.RuneCountInString(joined) == 1 {
+			single.WriteString(joined)
+		} else {
+			flush()
+		}
+	}
+	flush()
+
+	return result
+}
+
+// run - серия одинаковых букв подряд
+type run struct {
+	r rune
+	n int
+}
+
+// runs разбивает слово на серии одинаковых букв: "qqwerrty" -> q2 w1 e1 r2 t1 y1
+func runs(word string) []run {
+	var result []run
+	for _, r := range word {
+		if len(result) > 0 && result[len(result)-1].r == r {
+			result[len(result)-1].n++
+			continue
+		}
+		result = append(result, run{r: r, n: 1})
+	}
+	return result
+}
+
+// pattern - слово словаря, подготовленное для сравнения
+type pattern struct {
+	word   Word
+	runs   []run
+	prefix bool // слово с * на конце совпадает со всеми словами, которые с него начинаются
+}
+
+// newPatterns возвращает образцы для всех прочтений слова словаря из variants
+func newPatterns(w Word) []pattern {
+	word, prefix := strings.CutSuffix(w.Word, "*")
+	var result []pattern
+	for _, v := range variants(letters(word)) {
+		result = append(result, pattern{word: w, runs: runs(v), prefix: prefix})
+	}
+	return result
+}
+
+// matches сравнивает слово текста с образцом. Повторённые буквы не мешают совпадению
+// ("qqwwerrrty" совпадает с "qwerty"), но буква не может встречаться реже, чем
+// в образце: "as" не совпадает с "ass".
+func (p pattern) matches(token []run) bool {
+	if len(token) < len(p.runs) || (!p.prefix && len(token) != len(p.runs)) {
+		return false
+	}
+	for i, want := range p.runs {
+		if token[i].r != want.r || token[i].n < want.n {
+			return false
+		}
+	}
+	return true
+}
+
+// matcher - словарь, подготовленный для поиска запрещённых слов
+type matcher struct {
+	forbidden []pattern
+	allowed   []pattern // слова-исключения, которые не считаются запрещёнными
+}
+
+func newMatcher(words map[string]Word) *matcher {
+	m := &matcher{}
+	for _, w := range words {
+		if w.Allow {
+			m.allowed = append(m.allowed, newPatterns(w)...)
+		} else {
+			m.forbidden = append(m.forbidden, newPatterns(w)...)
+		}
+	}
+	return m
+}
+
+// match ищет в тексте запрещённые слова и возвращает самое строгое из найденных
+func (m *matcher) match(text string) (Word, bool) {
+	var found Word
+	ok := false
+
+	for _, token := range tokens(text) {
+		tokenRuns := runs(token)
+		if matchesAny(m.allowed, tokenRuns) {
+			continue
+		}
+		for _, p := range m.forbidden {
+			if !p.matches(tokenRuns) {
+				continue
+			}
+			w := p.word
+			// При равной строгости выбирается первое по алфавиту, чтобы результат не зависел от порядка обхода
+			if !ok || w.Severity > found.Severity || (w.Severity == found.Severity && w.Word < found.Word) {
+				found, ok = w, true
+			}
+		}
+	}
+	return found, ok
+}
+
+func matchesAny(patterns []pattern, token []run) bool {
+	for _, p := range patterns {
+		if p.matches(token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package main
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	words := make(map[string]Word)
+	for _, w := range []Word{
+		{Word: "qwerty", Severity: 1},
+		{Word: "йцукен", Severity: 1},
+		{Word: "cop", Severity: 2},
+		{Word: "bot", Severity: 1},
+		{Word: "hem", Severity: 1},
+		{Word: "nazi", Severity: 3},
+		{Word: "shit", Severity: 2},
+		{Word: "spam", Severity: 1},
+		{Word: "ass*", Severity: 3},
+		{Word: "assist*", Allow: true},
+	} {
+		if err := w.validate(); err != nil {
+			t.Fatalf("%q: %v", w.Word, err)
+		}
+		words[w.Word] = w
+	}
+	m := newMatcher(words)
+
+	for _, tc := range []struct {
+		name, text string
+		want       string // найденное слово; пустая строка - комментарий одобрен
+	}{
+		{"clean", "Отличная новость, спасибо", ""},
+		{"plain", "ну и qwerty", "qwerty"},
+		{"cyrillic word", "Это йцукен какой-то", "йцукен"},
+		{"upper case", "QWERTY!", "qwerty"},
+
+		// Безобидные слова, содержащие запрещённое
+		{"substring inside word", "A class of grass in the passage", ""},
+		{"substring with prefix", "antispam filter", ""},
+		{"robot", "Робот сказал привет", ""},
+		{"shorter than banned", "as good as new", ""},
+
+		// Смешение латиницы и кириллицы
+		{"cyrillic е in latin word", "qwеrty", "qwerty"},
+		{"latin letters in cyrillic word", "йцyкeн", "йцукен"},
+		{"cyrillic о in latin word", "cоp", "cop"},
+		{"greek ο in latin word", "bοt", "bot"},
+		{"fullwidth", "ｑｗｅｒｔｙ", "qwerty"},
+		{"math bold", "𝐪𝐰𝐞𝐫𝐭𝐲", "qwerty"},
+
+		// Вставленные знаки и пробелы
+		{"dots", "q.w.e.r.t.y", "qwerty"},
+		{"dashes", "й-ц-у-к-е-н", "йцукен"},
+		{"spaced letters", "q w e r t y", "qwerty"},
+		{"word and punctuation", "(qwerty),", "qwerty"},
+
+		// Повторённые буквы
+		{"repeated latin", "qqwweerrrtyyyy", "qwerty"},
+		{"repeated cyrillic", "йццуккеенн", "йцукен"},
+		{"repeated in prefix word", "aaassssss", "ass*"},
+
+		// Leetspeak
+		{"digits", "qw3rty", "qwerty"},
+		{"digits in cyrillic word", "йцук3н", "йцукен"},
+		{"symbol inside word", "sh!t", "shit"},
+		{"at sign", "n@zi", "nazi"},
+		{"leading dollar", "$pam", "spam"},
+		{"leading digit", "4ss", "ass*"},
+		{"zero", "b0t", "bot"},
+
+		// Исключения
+		{"allowed word", "My assistant is great", ""},
+		{"allowed prefix form", "Assisted living", ""},
+		{"banned despite allow-list", "what an asshole", "ass*"},
+
+		// Ложные срабатывания прежней нормализации
+		{"russian сор is not cop", "Не выноси сор из избы", ""},
+		{"russian вот is not bot", "Вот и всё", ""},
+		{"russian вот typed with latin o", "вoт", ""},
+		{"russian нем is not hem", "Что в нем?", ""},
+		{"number is not leetspeak", "Price: 455 rubles", ""},
+		{"digits only", "1337 0 5", ""},
+		{"trailing exclamation", "Hi, Naz!", ""},
+		{"trailing punctuation", "Really?! Wow!!", ""},
+
+		// Самое строгое из найденных слов
+		{"most severe", "qwerty cop ass", "ass*"},
+	} {
+		got, ok := m.match(tc.text)
+		if tc.want == "" {
+			if ok {
+				t.Errorf("%s: %q rejected for %q, want approved", tc.name, tc.text, got.Word)
+			}
+			continue
+		}
+		if !ok || got.Word != tc.want {
+			t.Errorf("%s: %q matched %q (found %v), want %q", tc.name, tc.text, got.Word, ok, tc.want)
+		}
+	}
+}
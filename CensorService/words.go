@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func writeWord(w http.ResponseWriter, status int, word Word) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(word); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// getWords возвращает словарь запрещённых слов; параметр category оставляет одну категорию
+func (api *API) getWords(w http.ResponseWriter, r *http.Request) {
+	words := api.dict.List(strings.TrimSpace(r.URL.Query().Get("category")))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(words); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// addWord добавляет слово в словарь или меняет категорию и строгость существующего
+func (api *API) addWord(w http.ResponseWriter, r *http.Request) {
+	var word Word
+	if err := json.NewDecoder(r.Body).Decode(&word); err != nil {
+		http.Error(w, "Failed to parse request body", http.StatusBadRequest)
+		return
+	}
+	if err := word.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := api.dict.Put(word)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save dictionary: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeWord(w, status, word)
+}
+
+// deleteWord удаляет слово из словаря. Слово нормализуется так же, как при добавлении,
+// чтобы удалить "Qwerty" или "ｑｗｅｒｔｙ" можно было тем же написанием.
+func (api *API) deleteWord(w http.ResponseWriter, r *http.Request) {
+	word := normalizeText(strings.TrimSpace(mux.Vars(r)["word"]))
+
+	found, err := api.dict.Delete(word)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save dictionary: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Word not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
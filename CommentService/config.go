@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"common/config"
+	"common/logging"
+	"common/postgres"
+	"common/tracing"
+)
+
+// Config - настройки сервиса комментариев из файла config.json и переменных окружения.
+//
+// Переменные окружения имеют приоритет над файлом:
+//
+//	COMMENTS_CONFIG             - путь к файлу конфигурации
+//	COMMENTS_LISTEN_ADDR        - адрес HTTP-сервера, например :8081
+//	COMMENTS_LOG_LEVEL - уровень журнала: debug, info, warn или error
+//	COMMENTS_DATABASE_DSN       - строка подключения к PostgreSQL
+//	COMMENTS_DB_MAX_CONNS       - максимальный размер пула соединений
+//	COMMENTS_DB_MIN_CONNS       - минимальный размер пула соединений
+//	COMMENTS_DB_CONNECT_TIMEOUT - таймаут подключения к БД, например 5s
+//	COMMENTS_DB_AUTO_MIGRATE    - применять миграции при запуске: true или false
+//	COMMENTS_READ_TIMEOUT, COMMENTS_WRITE_TIMEOUT, COMMENTS_IDLE_TIMEOUT - таймауты HTTP-сервера
+//	COMMENTS_SHUTDOWN_TIMEOUT - время на завершение текущих запросов при остановке
+//	COMMENTS_TRACING_EXPORTER - экспорт трассировки: none, stdout (спаны пишутся в stderr) или otlp
+//	COMMENTS_TRACING_ENDPOINT - адрес приёма спанов OTLP/HTTP, например http://localhost:4318/v1/traces
+//	COMMENTS_TRACING_SAMPLE_RATIO - доля трассируемых запросов от 0 до 1
+type Config struct {
+	Listen   string          `json:"listen"`
+	LogLevel string          `json:"log_level"`
+	Database postgres.Config `json:"database"`
+	Server   config.Server   `json:"server"`
+	Tracing  tracing.Config  `json:"tracing"`
+}
+
+const configPath = "config.json"
+
+// loadConfig читает конфигурацию из файла и переменных окружения и проверяет её.
+// Файл по умолчанию может отсутствовать, если всё задано переменными окружения.
+func loadConfig() (Config, error) {
+	cfg := Config{
+		Listen:   ":8081",
+		LogLevel: "info",
+		Database: postgres.DefaultConfig(),
+		Server:   config.DefaultServer(),
+		Tracing:  tracing.DefaultConfig(),
+	}
+
+	if err := config.Load("COMMENTS_CONFIG", configPath, &cfg); err != nil {
+		return cfg, err
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// applyEnv переопределяет настройки значениями из переменных окружения
+func (cfg *Config) applyEnv() error {
+	return config.ApplyEnv(cfg.Server.Env("COMMENTS_"), cfg.Tracing.Env("COMMENTS_"), cfg.Database.Env("COMMENTS_"), map[string]any{
+		"COMMENTS_LISTEN_ADDR": &cfg.Listen,
+		"COMMENTS_LOG_LEVEL":   &cfg.LogLevel,
+	})
+}
+
+func (cfg *Config) validate() error {
+	if cfg.Listen == "" {
+		return errors.New("listen address is required (listen or COMMENTS_LISTEN_ADDR)")
+	}
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		return fmt.Errorf("invalid log_level %q: must be debug, info, warn or error", cfg.LogLevel)
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		return err
+	}
+
+	if err := config.Positive(cfg.Server.Durations(), cfg.Database.Durations()); err != nil {
+		return err
+	}
+
+	if err := cfg.Tracing.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
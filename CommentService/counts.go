@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Максимальное количество новостей в одном запросе /comments/counts
+const maxCountsBatch = 100
+
+// getCommentCounts возвращает количество неудалённых комментариев для списка новостей:
+// GET /comments/counts?news_id=1,2,3 -> {"1": 5, "2": 0, "3": 12}
+func (api *API) getCommentCounts(w http.ResponseWriter, r *http.Request) {
+	param := r.URL.Query().Get("news_id")
+	if param == "" {
+		http.Error(w, "news_id is required", http.StatusBadRequest)
+		return
+	}
+
+	parts := strings.Split(param, ",")
+	if len(parts) > maxCountsBatch {
+		http.Error(w, fmt.Sprintf("Too many news_id values: at most %d allowed", maxCountsBatch), http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]int, 0, len(parts))
+	counts := make(map[string]int, len(parts))
+	for _, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid news_id: %q", part), http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+		counts[strconv.Itoa(id)] = 0
+	}
+
+	rows, err := api.db.Query(r.Context(), `
+	SELECT news_id, COUNT(*) FROM comments
+	WHERE news_id = ANY($1::int[]) AND NOT deleted
+	GROUP BY news_id;
+	`, ids)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch comment counts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var newsID, count int
+		if err := rows.Scan(&newsID, &count); err != nil {
+			http.Error(w, fmt.Sprintf("Error scanning comment count: %v", err), http.StatusInternalServerError)
+			return
+		}
+		counts[strconv.Itoa(newsID)] = count
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(counts); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
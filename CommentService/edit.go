@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+// CommentEdit - предыдущая версия текста комментария
+type CommentEdit struct {
+	Text     string    `json:"text"`
+	EditedAt time.Time `json:"edited_at"` // когда текст был заменён
+}
+
+// commentIDs разбирает NewsID и CommentID из пути запроса
+func commentIDs(r *http.Request) (newsID, commentID int, err error) {
+	params := mux.Vars(r)
+	if newsID, err = strconv.Atoi(params["NewsID"]); err != nil {
+		return 0, 0, errors.New("Invalid NewsID")
+	}
+	if commentID, err = strconv.Atoi(params["CommentID"]); err != nil {
+		return 0, 0, errors.New("Invalid CommentID")
+	}
+	return newsID, commentID, nil
+}
+
+// editComment заменяет текст комментария, сохраняя предыдущую версию в comment_edits
+func (api *API) editComment(w http.ResponseWriter, r *http.Request) {
+	newsID, commentID, err := commentIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Failed to parse request body", http.StatusBadRequest)
+		return
+	}
+	if body.Text == "" {
+		http.Error(w, "Text is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := api.db.Begin(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to begin transaction: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var oldText string
+	var deleted bool
+	err = tx.QueryRow(ctx, `
+	SELECT text, deleted FROM comments
+	WHERE id = $1 AND news_id = $2
+	FOR UPDATE;
+	`, commentID, newsID).Scan(&oldText, &deleted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch comment: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if deleted {
+		http.Error(w, "Comment is deleted", http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	if _, err := tx.Exec(ctx, `
+	INSERT INTO comment_edits (comment_id, text, edited_at)
+	VALUES ($1, $2, $3);
+	`, commentID, oldText, now); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save edit history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var comment Comment
+	err = tx.QueryRow(ctx, `
+	UPDATE comments SET text = $2, edited_at = $3
+	WHERE id = $1
+	RETURNING `+commentColumns+`;
+	`, commentID, body.Text, now).Scan(comment.scanFields()...)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update comment: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update comment: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(comment); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// deleteComment помечает комментарий удалённым. Запись остаётся в таблице, чтобы
+// ответы на неё сохранили своё место в дереве. Повторное удаление возвращает 410.
+// У родителя удалённого ответа в той же транзакции уменьшается reply_count.
+func (api *API) deleteComment(w http.ResponseWriter, r *http.Request) {
+	newsID, commentID, err := commentIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := api.db.Begin(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to begin transaction: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var wasDeleted bool
+	var parentID *int
+	err = tx.QueryRow(ctx, `
+	UPDATE comments c SET deleted = TRUE, deleted_at = COALESCE(c.deleted_at, $3)
+	FROM (SELECT id, deleted FROM comments WHERE id = $1 AND news_id = $2 FOR UPDATE) prev
+	WHERE c.id = prev.id
+	RETURNING prev.deleted, c.parent_id;
+	`, commentID, newsID, time.Now()).Scan(&wasDeleted, &parentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete comment: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if wasDeleted {
+		http.Error(w, "Comment is already deleted", http.StatusGone)
+		return
+	}
+
+	if parentID != nil {
+		_, err = tx.Exec(ctx, `
+		UPDATE comments SET reply_count = reply_count - 1
+		WHERE id = $1 AND reply_count > 0;
+		`, *parentID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to update reply count: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete comment: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getCommentEdits возвращает предыдущие версии текста комментария, начиная с последней
+func (api *API) getCommentEdits(w http.ResponseWriter, r *http.Request) {
+	newsID, commentID, err := commentIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var deleted bool
+	err = api.db.QueryRow(r.Context(), `
+	SELECT deleted FROM comments
+	WHERE id = $1 AND news_id = $2;
+	`, commentID, newsID).Scan(&deleted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch comment: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if deleted {
+		http.Error(w, "Comment is deleted", http.StatusGone)
+		return
+	}
+
+	rows, err := api.db.Query(r.Context(), `
+	SELECT text, edited_at FROM comment_edits
+	WHERE comment_id = $1
+	ORDER BY edited_at DESC, id DESC;
+	`, commentID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch edit history: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	edits := []CommentEdit{}
+	for rows.Next() {
+		var edit CommentEdit
+		if err := rows.Scan(&edit.Text, &edit.EditedAt); err != nil {
+			http.Error(w, fmt.Sprintf("Error scanning edit: %v", err), http.StatusInternalServerError)
+			return
+		}
+		edits = append(edits, edit)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(edits); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
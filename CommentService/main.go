@@ -3,23 +3,48 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
+	"common/logging"
+	"common/middleware"
+	"common/postgres"
+	"common/tracing"
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
 type Comment struct {
-	ID        int       `json:"id"`
-	NewsID    int       `json:"news_id"`
-	Author    string    `json:"author"`
-	Text      string    `json:"text"`
-	ParentID  int       `json:"parent_id"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int        `json:"id"`
+	NewsID    int        `json:"news_id"`
+	Author    string     `json:"author"`
+	Text      string     `json:"text"`
+	ParentID  int        `json:"parent_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	Deleted   bool       `json:"deleted"` // удалённый комментарий остаётся в дереве без автора и текста
+}
+
+// commentColumns - поля комментария для SELECT; у удалённых комментариев скрываются автор и текст
+const commentColumns = `id, news_id,
+	CASE WHEN deleted THEN '' ELSE author END,
+	CASE WHEN deleted THEN '' ELSE text END,
+	COALESCE(parent_id, 0), created_at, edited_at, deleted`
+
+// scanFields возвращает указатели на поля в порядке commentColumns
+func (c *Comment) scanFields() []interface{} {
+	return []interface{}{&c.ID, &c.NewsID, &c.Author, &c.Text, &c.ParentID, &c.CreatedAt, &c.EditedAt, &c.Deleted}
 }
 
 type API struct {
@@ -41,50 +66,53 @@ func (api *API) Router() *mux.Router {
 }
 
 func (api *API) endpoints() {
+	api.r.HandleFunc("/healthz", postgres.Healthz).Methods(http.MethodGet)
+	api.r.HandleFunc("/readyz", postgres.Readyz(api.db)).Methods(http.MethodGet)
+	api.r.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+	// /comments/counts регистрируется до /comments/{NewsID}, чтобы не совпасть с ним
+	api.r.HandleFunc("/comments/counts", api.getCommentCounts).Methods(http.MethodGet)
 	api.r.HandleFunc("/comments/{NewsID}", api.getComments).Methods(http.MethodGet)
 	api.r.HandleFunc("/comments/{NewsID}", api.addComment).Methods(http.MethodPost)
+	api.r.HandleFunc("/comments/{NewsID}/{CommentID}", api.editComment).Methods(http.MethodPut)
+	api.r.HandleFunc("/comments/{NewsID}/{CommentID}", api.deleteComment).Methods(http.MethodDelete)
+	api.r.HandleFunc("/comments/{NewsID}/{CommentID}/edits", api.getCommentEdits).Methods(http.MethodGet)
 }
 
-func initDB() *pgxpool.Pool {
-	var err error
-
-	connString := "host=localhost port=5432 user=postgres password=postgres dbname=Comments sslmode=disable" // Замените username, password, dbname
-
-	db, err := pgxpool.Connect(context.Background(), connString)
+// getComments возвращает страницу комментариев к новости: плоским списком (по умолчанию)
+// или деревом ответов при view=tree. Общее количество и курсор следующей страницы
+// передаются в заголовках X-Total-Count и X-Next-Cursor.
+func (api *API) getComments(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	newsID, err := strconv.Atoi(params["NewsID"])
 	if err != nil {
-		log.Fatalf("Unable to connect to database: %v\n", err)
+		http.Error(w, "Invalid NewsID", http.StatusBadRequest)
+		return
 	}
 
-	return db
-}
-
-func (api *API) getComments(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	newsID := params["NewsID"]
+	view := r.URL.Query().Get("view")
+	if view != "" && view != "flat" && view != "tree" {
+		http.Error(w, "Invalid view parameter: must be flat or tree", http.StatusBadRequest)
+		return
+	}
 
-	rows, err := api.db.Query(context.Background(), `
-	SELECT * FROM comments
-	WHERE news_id = $1
-	ORDER BY created_at DESC;
-	`, newsID)
+	page, err := parseCommentPage(r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to fetch comments: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer rows.Close()
 
-	var comments []Comment
-	for rows.Next() {
-		var comment Comment
-
-		if err := rows.Scan(&comment.ID, &comment.NewsID, &comment.Author, &comment.Text, &comment.ParentID, &comment.CreatedAt); err != nil {
-			http.Error(w, fmt.Sprintf("Error scanning comment: %v", err), http.StatusInternalServerError)
-			return
-		}
+	if view == "tree" {
+		api.getCommentTree(w, r, newsID, page)
+		return
+	}
 
-		comments = append(comments, comment)
+	comments, total, next, err := api.listComments(r.Context(), newsID, false, page)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch comments: %v", err), http.StatusInternalServerError)
+		return
 	}
 
+	setPageHeaders(w, total, next)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(comments); err != nil {
@@ -99,11 +127,10 @@ func (api *API) addComment(w http.ResponseWriter, r *http.Request) {
 	var comment Comment
 	err := json.NewDecoder(r.Body).Decode(&comment)
 	if err != nil {
-		fmt.Println(comment)
 		http.Error(w, "Failed to parse request body", http.StatusBadRequest)
 		return
 	}
-	log.Println(comment)
+	slog.DebugContext(r.Context(), "Adding comment", "news_id", newsID, "parent_id", comment.ParentID, "author", comment.Author)
 
 	// Проверка обязательных полей
 	if comment.Text == "" || comment.Author == "" {
@@ -120,14 +147,54 @@ func (api *API) addComment(w http.ResponseWriter, r *http.Request) {
 	comment.NewsID = newsIDInt
 	comment.CreatedAt = time.Now()
 
-	if comment.ParentID == 0 {
-		comment.ParentID = 0
+	// Ответ должен ссылаться на существующий комментарий к той же новости
+	if comment.ParentID < 0 {
+		http.Error(w, "Invalid parent_id", http.StatusBadRequest)
+		return
 	}
 
-	_, err = api.db.Exec(
-		context.Background(),
+	ctx := r.Context()
+	tx, err := api.db.Begin(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to begin transaction: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if comment.ParentID != 0 {
+		// Родитель блокируется до конца транзакции, чтобы его нельзя было удалить,
+		// пока к нему добавляется ответ, и чтобы reply_count не потерял обновление
+		var parentNewsID int
+		var parentDeleted bool
+		err = tx.QueryRow(ctx, `
+		SELECT news_id, deleted FROM comments
+		WHERE id = $1
+		FOR UPDATE;
+		`, comment.ParentID).Scan(&parentNewsID, &parentDeleted)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Parent comment not found", http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to fetch parent comment: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if parentNewsID != comment.NewsID {
+			http.Error(w, "Parent comment belongs to another news", http.StatusUnprocessableEntity)
+			return
+		}
+		if parentDeleted {
+			http.Error(w, "Parent comment is deleted", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	// parent_id ссылается на comments(id) (миграция 0003), поэтому у комментария
+	// верхнего уровня он NULL; в API по-прежнему передаётся 0
+	_, err = tx.Exec(
+		ctx,
 		`INSERT INTO comments (news_id, text, parent_id, created_at, author) 
-         VALUES ($1, $2, $3, $4, $5)`,
+         VALUES ($1, $2, NULLIF($3, 0), $4, $5)`,
 		comment.NewsID, comment.Text, comment.ParentID, comment.CreatedAt, comment.Author,
 	)
 	if err != nil {
@@ -135,59 +202,88 @@ func (api *API) addComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if comment.ParentID != 0 {
+		_, err = tx.Exec(ctx, `
+		UPDATE comments SET reply_count = reply_count + 1
+		WHERE id = $1;
+		`, comment.ParentID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to update reply count: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to insert comment: %v", err), http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	w.Write([]byte(`{"status": "success"}`))
 }
 
-func generateRequestID() string {
-	return fmt.Sprintf("%d", time.Now().UnixNano())
-}
+func main() {
+	cfg, err := loadConfig()
+	logging.Setup(cfg.LogLevel, "comments")
+	if err != nil {
+		logging.Fatal("Failed to load config", "error", err)
+	}
 
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-// WriteHeader записывает HTTP-статус и сохраняет его в переменную
-func (rw *responseWriter) WriteHeader(statusCode int) {
-	rw.statusCode = statusCode
-	rw.ResponseWriter.WriteHeader(statusCode)
-}
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, "comments")
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
 
-// Write записывает тело ответа
-func (rw *responseWriter) Write(p []byte) (n int, err error) {
-	return rw.ResponseWriter.Write(p)
-}
+	db, err := postgres.Connect(ctx, cfg.Database, cfg.Tracing.Enabled())
+	if err != nil {
+		logging.Fatal("Unable to connect to database", "error", err)
+	}
+	defer db.Close()
 
-func HeadersMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := r.URL.Query().Get("request_id")
-		if requestID == "" {
-			requestID = generateRequestID()
+	// Подкоманда migrate up|down [N]|status выполняется вместо запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrations.Run(ctx, db, os.Args[2:]); err != nil {
+			logging.Fatal("Migration failed", "error", err)
 		}
+		return
+	}
+	prometheus.MustRegister(postgres.NewPoolCollector(db))
 
-		wrappedWriter := &responseWriter{ResponseWriter: w}
-
-		next.ServeHTTP(wrappedWriter, r)
-
-		log.Printf(
-			"Request ID: %s | Time: %s | IP: %s | Status: %d",
-			requestID,
-			time.Now().Format(time.RFC3339),
-			r.RemoteAddr,
-			wrappedWriter.statusCode,
-		)
-
-	})
-}
-
-func main() {
-	db := initDB()
-	defer db.Close()
+	if cfg.Database.AutoMigrate {
+		if err := migrations.Up(ctx, db); err != nil {
+			logging.Fatal("Failed to apply migrations", "error", err)
+		}
+	}
 
 	api := NewAPI(db)
-	api.Router().Use(HeadersMiddleware)
-	http.Handle("/", api.Router())
-	fmt.Println("Server started at http://localhost:8081/")
-	log.Fatal(http.ListenAndServe(":8081", api.r))
+	middleware.Use(api.Router(), otelmux.Middleware("comments"))
+	srv := &http.Server{
+		Addr:         cfg.Listen,
+		Handler:      api.Router(),
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout),
+	}
+	go func() {
+		slog.Info("Server started", "addr", cfg.Listen)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("Server failed", "error", err)
+		}
+	}()
+
+	// По SIGINT или SIGTERM сервер перестаёт принимать соединения и ждёт
+	// завершения текущих запросов не дольше shutdown_timeout
+	<-ctx.Done()
+	slog.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Graceful shutdown failed", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
 }
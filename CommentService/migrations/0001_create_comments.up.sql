@@ -0,0 +1,10 @@
+-- Исходная схема; IF NOT EXISTS позволяет применить миграцию к базе,
+-- созданной вручную из comm_create.sql
+CREATE TABLE IF NOT EXISTS comments (
+    id SERIAL PRIMARY KEY,
+    news_id INT NOT NULL,
+    author VARCHAR(255) NOT NULL,
+    text TEXT NOT NULL,
+    parent_id INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
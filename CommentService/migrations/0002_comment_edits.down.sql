@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS comment_edits;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS deleted,
+    DROP COLUMN IF EXISTS edited_at;
@@ -0,0 +1,12 @@
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Предыдущие версии текста отредактированных комментариев
+CREATE TABLE IF NOT EXISTS comment_edits (
+    id SERIAL PRIMARY KEY,
+    comment_id INT NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    edited_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
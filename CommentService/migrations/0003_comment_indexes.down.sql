@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS comment_edits_comment_id_idx;
+DROP INDEX IF EXISTS comments_parent_id_idx;
+DROP INDEX IF EXISTS comments_news_id_created_at_idx;
+
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS comments_parent_id_fkey;
+-- Версии до 0003 сохраняют комментарии верхнего уровня с parent_id = 0
+-- и ищут их по этому значению
+UPDATE comments SET parent_id = 0 WHERE parent_id IS NULL;
@@ -0,0 +1,13 @@
+-- Раньше комментарии верхнего уровня сохранялись с parent_id = 0
+UPDATE comments SET parent_id = NULL WHERE parent_id = 0;
+
+-- NOT VALID: ответы на отсутствующие комментарии, сохранённые до проверки
+-- parent_id, не мешают применению миграции; новые записи проверяются
+ALTER TABLE comments
+    ADD CONSTRAINT comments_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES comments(id) NOT VALID;
+
+-- news_id ссылается на базу сервиса новостей, поэтому внешнего ключа для него нет
+
+CREATE INDEX IF NOT EXISTS comments_news_id_created_at_idx ON comments (news_id, created_at, id);
+CREATE INDEX IF NOT EXISTS comments_parent_id_idx ON comments (parent_id);
+CREATE INDEX IF NOT EXISTS comment_edits_comment_id_idx ON comment_edits (comment_id, edited_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS comments_news_id_reply_count_idx;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS reply_count;
@@ -0,0 +1,13 @@
+-- Количество неудалённых ответов, по которому работает sort=top. Обновляется
+-- в той же транзакции, что добавление и удаление ответа.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS reply_count INT NOT NULL DEFAULT 0;
+
+UPDATE comments c SET reply_count = r.count
+FROM (
+    SELECT parent_id, COUNT(*) AS count FROM comments
+    WHERE parent_id IS NOT NULL AND NOT deleted
+    GROUP BY parent_id
+) r
+WHERE c.id = r.parent_id;
+
+CREATE INDEX IF NOT EXISTS comments_news_id_reply_count_idx ON comments (news_id, reply_count, id);
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultCommentsLimit = 20
+	maxCommentsLimit     = 100
+)
+
+// commentSort описывает порядок вывода комментариев
+type commentSort struct {
+	expr    string // SQL-выражение ключа сортировки
+	keyType string // тип, к которому приводится значение ключа из курсора
+	order   string
+}
+
+var commentSorts = map[string]commentSort{
+	"newest": {expr: "c.created_at", keyType: "timestamp", order: "desc"},
+	"oldest": {expr: "c.created_at", keyType: "timestamp", order: "asc"},
+	"top":    {expr: "c.reply_count", keyType: "int", order: "desc"}, // количество неудалённых ответов
+}
+
+// commentCursor - позиция в списке комментариев, передаётся клиенту непрозрачной строкой
+type commentCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"` // значение ключа сортировки в текстовом представлении PostgreSQL
+	ID   int    `json:"id"`
+}
+
+func (c commentCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCommentCursor(s string) (commentCursor, error) {
+	var c commentCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("Invalid cursor parameter")
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return c, errors.New("Invalid cursor parameter")
+	}
+	if _, ok := commentSorts[c.Sort]; !ok {
+		return c, errors.New("Invalid cursor parameter")
+	}
+
+	return c, nil
+}
+
+// commentPage - параметры страницы комментариев: limit, sort и cursor
+type commentPage struct {
+	Limit  int
+	Sort   string
+	Cursor *commentCursor
+}
+
+func parseCommentPage(r *http.Request) (commentPage, error) {
+	params := r.URL.Query()
+	p := commentPage{Limit: defaultCommentsLimit, Sort: "newest"}
+
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > maxCommentsLimit {
+			return p, fmt.Errorf("Invalid limit parameter: must be between 1 and %d", maxCommentsLimit)
+		}
+		p.Limit = n
+	}
+
+	if sort := params.Get("sort"); sort != "" {
+		if _, ok := commentSorts[sort]; !ok {
+			return p, errors.New("Invalid sort parameter: must be newest, oldest or top")
+		}
+		p.Sort = sort
+	}
+
+	if cursor := params.Get("cursor"); cursor != "" {
+		c, err := decodeCommentCursor(cursor)
+		if err != nil {
+			return p, err
+		}
+		if params.Get("sort") != "" && c.Sort != p.Sort {
+			return p, errors.New("Invalid cursor parameter: cursor does not match sort")
+		}
+		p.Sort = c.Sort
+		p.Cursor = &c
+	}
+
+	return p, nil
+}
+
+// listComments возвращает страницу комментариев к новости (при rootsOnly - только
+// комментарии верхнего уровня), их общее количество и курсор следующей страницы
+func (api *API) listComments(ctx context.Context, newsID int, rootsOnly bool, p commentPage) ([]Comment, int, string, error) {
+	args := []interface{}{newsID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := "c.news_id = $1"
+	if rootsOnly {
+		where += " AND c.parent_id IS NULL"
+	}
+
+	var total int
+	err := api.db.QueryRow(ctx, `SELECT COUNT(*) FROM comments c WHERE `+where+`;`, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	spec := commentSorts[p.Sort]
+	if p.Cursor != nil {
+		op := "<"
+		if spec.order == "asc" {
+			op = ">"
+		}
+		where += fmt.Sprintf(" AND (%s, c.id) %s (%s::%s, %s)", spec.expr, op, arg(p.Cursor.Key), spec.keyType, arg(p.Cursor.ID))
+	}
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	rows, err := api.db.Query(ctx, fmt.Sprintf(`
+	SELECT %s, (%s)::text FROM comments c
+	WHERE %s
+	ORDER BY %s %s, c.id %s
+	LIMIT %d;
+	`, commentColumns, spec.expr, where, spec.expr, spec.order, spec.order, p.Limit+1), args...)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	var sortKeys []string
+	for rows.Next() {
+		var comment Comment
+		var sortKey string
+		if err := rows.Scan(append(comment.scanFields(), &sortKey)...); err != nil {
+			return nil, 0, "", err
+		}
+		comments = append(comments, comment)
+		sortKeys = append(sortKeys, sortKey)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, "", err
+	}
+
+	var next string
+	if len(comments) > p.Limit {
+		comments = comments[:p.Limit]
+		last := len(comments) - 1
+		next = commentCursor{Sort: p.Sort, Key: sortKeys[last], ID: comments[last].ID}.Encode()
+	}
+
+	return comments, total, next, nil
+}
+
+// setPageHeaders сообщает клиенту общее количество и курсор следующей страницы
+func setPageHeaders(w http.ResponseWriter, total int, next string) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestDecodeCommentCursor(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	for _, tc := range []struct {
+		name   string
+		cursor string
+		ok     bool
+	}{
+		{"valid", commentCursor{Sort: "top", Key: "3", ID: 7}.Encode(), true},
+		{"bad base64", "not base64!", false},
+		{"bad json", raw(`{"s": "top"`), false},
+		{"zero id", raw(`{"s": "top", "k": "3", "id": 0}`), false},
+		{"negative id", raw(`{"s": "top", "k": "3", "id": -1}`), false},
+		{"unknown sort", raw(`{"s": "best", "k": "3", "id": 7}`), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := decodeCommentCursor(tc.cursor)
+			if tc.ok {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if c != (commentCursor{Sort: "top", Key: "3", ID: 7}) {
+					t.Fatalf("got %+v", c)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("got %+v, want an error", c)
+			}
+		})
+	}
+}
+
+func TestParseCommentPage(t *testing.T) {
+	topCursor := commentCursor{Sort: "top", Key: "3", ID: 7}.Encode()
+
+	for _, tc := range []struct {
+		name     string
+		params   url.Values
+		ok       bool
+		wantSort string
+		wantSize int
+	}{
+		{"defaults", url.Values{}, true, "newest", defaultCommentsLimit},
+		{"min limit", url.Values{"limit": {"1"}}, true, "newest", 1},
+		{"max limit", url.Values{"limit": {"100"}}, true, "newest", maxCommentsLimit},
+		{"zero limit", url.Values{"limit": {"0"}}, false, "", 0},
+		{"limit over max", url.Values{"limit": {"101"}}, false, "", 0},
+		{"limit not a number", url.Values{"limit": {"ten"}}, false, "", 0},
+		{"unknown sort", url.Values{"sort": {"best"}}, false, "", 0},
+		{"cursor sets sort", url.Values{"cursor": {topCursor}}, true, "top", defaultCommentsLimit},
+		{"cursor matches sort", url.Values{"sort": {"top"}, "cursor": {topCursor}}, true, "top", defaultCommentsLimit},
+		{"cursor does not match sort", url.Values{"sort": {"oldest"}, "cursor": {topCursor}}, false, "", 0},
+		{"invalid cursor", url.Values{"cursor": {"garbage"}}, false, "", 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/news/1/comments?"+tc.params.Encode(), nil)
+			p, err := parseCommentPage(r)
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Sort != tc.wantSort || p.Limit != tc.wantSize {
+				t.Fatalf("got sort %q limit %d, want %q %d", p.Sort, p.Limit, tc.wantSort, tc.wantSize)
+			}
+			if (p.Cursor != nil) != tc.params.Has("cursor") {
+				t.Fatalf("cursor = %+v", p.Cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// CommentNode - комментарий с вложенными ответами
+type CommentNode struct {
+	Comment
+	Depth      int            `json:"depth"`       // уровень вложенности, 0 - ответ на новость
+	ReplyCount int            `json:"reply_count"` // количество прямых неудалённых ответов
+	Replies    []*CommentNode `json:"replies"`
+}
+
+// Ограничение глубины рекурсии на случай циклических ссылок parent_id
+const maxTreeDepth = 100
+
+// buildCommentTree собирает дерево из узлов, упорядоченных по глубине и дате.
+// Корневые комментарии возвращаются в порядке rootIDs, ответы - в порядке написания.
+func buildCommentTree(nodes []*CommentNode, rootIDs []int) []*CommentNode {
+	byID := make(map[int]*CommentNode, len(nodes))
+
+	for _, node := range nodes {
+		byID[node.ID] = node
+		if node.Depth == 0 {
+			continue
+		}
+		if parent, ok := byID[node.ParentID]; ok {
+			parent.Replies = append(parent.Replies, node)
+			// Удалённые ответы остаются в дереве, но не учитываются, как и в столбце reply_count
+			if !node.Deleted {
+				parent.ReplyCount++
+			}
+		}
+	}
+
+	roots := make([]*CommentNode, 0, len(rootIDs))
+	for _, id := range rootIDs {
+		if node, ok := byID[id]; ok {
+			roots = append(roots, node)
+		}
+	}
+
+	return roots
+}
+
+// getCommentTree возвращает страницу комментариев верхнего уровня вместе со всеми ответами на них
+func (api *API) getCommentTree(w http.ResponseWriter, r *http.Request, newsID int, page commentPage) {
+	roots, total, next, err := api.listComments(r.Context(), newsID, true, page)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch comments: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	rootIDs := make([]int, len(roots))
+	for i, root := range roots {
+		rootIDs[i] = root.ID
+	}
+
+	rows, err := api.db.Query(r.Context(), `
+	WITH RECURSIVE tree AS (
+		SELECT id, news_id, author, text, parent_id, created_at, edited_at, deleted, 0 AS depth
+		FROM comments
+		WHERE id = ANY($1::int[])
+		UNION ALL
+		SELECT c.id, c.news_id, c.author, c.text, c.parent_id, c.created_at, c.edited_at, c.deleted, t.depth + 1
+		FROM comments c
+		JOIN tree t ON c.parent_id = t.id
+		WHERE c.news_id = $2 AND t.depth < $3
+	)
+	SELECT `+commentColumns+`, depth FROM tree
+	ORDER BY depth, created_at, id;
+	`, rootIDs, newsID, maxTreeDepth)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch comments: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var nodes []*CommentNode
+	for rows.Next() {
+		node := &CommentNode{Replies: []*CommentNode{}}
+
+		if err := rows.Scan(append(node.scanFields(), &node.Depth)...); err != nil {
+			http.Error(w, fmt.Sprintf("Error scanning comment: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	setPageHeaders(w, total, next)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(buildCommentTree(nodes, rootIDs)); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func node(id, parentID, depth int, deleted bool) *CommentNode {
+	return &CommentNode{
+		Comment: Comment{ID: id, ParentID: parentID, Deleted: deleted},
+		Depth:   depth,
+		Replies: []*CommentNode{},
+	}
+}
+
+// ids возвращает дерево в виде id: [id ответов...] для сравнения
+func ids(nodes []*CommentNode, tree map[int][]int) []int {
+	out := []int{}
+	for _, n := range nodes {
+		out = append(out, n.ID)
+		tree[n.ID] = ids(n.Replies, tree)
+	}
+	return out
+}
+
+func TestBuildCommentTree(t *testing.T) {
+	// Узлы упорядочены по глубине и дате, как их возвращает запрос getCommentTree
+	nodes := []*CommentNode{
+		node(1, 0, 0, false),
+		node(2, 0, 0, false),
+		node(3, 1, 1, false),
+		node(4, 2, 1, true),
+		node(5, 1, 1, false),
+		node(6, 2, 1, false),
+		node(7, 3, 2, false),
+		node(8, 99, 1, false), // родитель не попал в выборку
+	}
+	// Корни в порядке страницы, например sort=top
+	roots := buildCommentTree(nodes, []int{2, 1})
+
+	tree := map[int][]int{}
+	if got := ids(roots, tree); !reflect.DeepEqual(got, []int{2, 1}) {
+		t.Fatalf("roots = %v, want [2 1]", got)
+	}
+	want := map[int][]int{
+		2: {4, 6},
+		1: {3, 5},
+		4: {},
+		6: {},
+		3: {7},
+		5: {},
+		7: {},
+	}
+	if !reflect.DeepEqual(tree, want) {
+		t.Errorf("tree = %v, want %v", tree, want)
+	}
+
+	byID := map[int]*CommentNode{}
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+	// Удалённый ответ 4 остаётся в дереве, но не учитывается в reply_count
+	for id, count := range map[int]int{1: 2, 2: 1, 3: 1, 4: 0, 7: 0} {
+		if got := byID[id].ReplyCount; got != count {
+			t.Errorf("comment %d: reply_count = %d, want %d", id, got, count)
+		}
+	}
+}
+
+func TestBuildCommentTreeMissingRoot(t *testing.T) {
+	roots := buildCommentTree([]*CommentNode{node(1, 0, 0, false)}, []int{5, 1})
+	if len(roots) != 1 || roots[0].ID != 1 {
+		t.Errorf("roots = %+v, want only comment 1", roots)
+	}
+}
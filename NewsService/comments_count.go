@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"common/requestid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Максимальное количество новостей в одном запросе к /comments/counts CommentService
+const maxCountsBatch = 100
+
+// commentCounts сверяет news.comments_count, по которому работает sort=comments,
+// с количеством комментариев в CommentService. Источник истины - CommentService:
+// счётчик не увеличивается и не уменьшается на разницу, а перезаписывается его данными,
+// поэтому пропущенное обновление исправляется при следующей сверке.
+type commentCounts struct {
+	db      *pgxpool.Pool
+	client  *http.Client
+	baseURL string        // пустой адрес отключает сверку
+	window  time.Duration // периодически сверяются только новости не старше window
+}
+
+func newCommentCounts(db *pgxpool.Pool, cfg CommentsConfig) *commentCounts {
+	return &commentCounts{
+		db: db,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+		baseURL: cfg.URL,
+		window:  time.Duration(cfg.SyncWindow),
+	}
+}
+
+// fetch запрашивает в CommentService количество комментариев к новостям ids
+func (c *commentCounts) fetch(ctx context.Context, ids []int) (map[int]int, error) {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/comments/counts?news_id="+strings.Join(parts, ","), nil)
+	if err != nil {
+		return nil, err
+	}
+	requestid.Set(req)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from comment service: %s", resp.Status)
+	}
+
+	var byID map[string]int
+	if err := json.NewDecoder(resp.Body).Decode(&byID); err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int, len(byID))
+	for key, count := range byID {
+		if id, err := strconv.Atoi(key); err == nil {
+			counts[id] = count
+		}
+	}
+	return counts, nil
+}
+
+// sync перезаписывает счётчики новостей ids и возвращает количество изменённых.
+// Новости, которых нет в ответе CommentService, не меняются.
+func (c *commentCounts) sync(ctx context.Context, ids []int) (int, error) {
+	counts, err := c.fetch(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	newsIDs := make([]int, 0, len(counts))
+	values := make([]int, 0, len(counts))
+	for id, count := range counts {
+		newsIDs = append(newsIDs, id)
+		values = append(values, count)
+	}
+
+	tag, err := c.db.Exec(ctx, `
+	UPDATE news n SET comments_count = c.count
+	FROM unnest($1::int[], $2::int[]) AS c(id, count)
+	WHERE n.id = c.id AND n.comments_count <> c.count;
+	`, newsIDs, values)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// syncRecent сверяет пачками по maxCountsBatch счётчики новостей, опубликованных
+// за последние window. Объём работы зависит от потока новостей, а не от размера архива.
+func (c *commentCounts) syncRecent(ctx context.Context) (int, error) {
+	since := time.Now().Add(-c.window).UTC()
+	updated, after := 0, 0
+	for {
+		rows, err := c.db.Query(ctx, `
+		SELECT id FROM news
+		WHERE created_at >= $1 AND id > $2
+		ORDER BY id
+		LIMIT $3;
+		`, since, after, maxCountsBatch)
+		if err != nil {
+			return updated, err
+		}
+		ids := make([]int, 0, maxCountsBatch)
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return updated, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, err
+		}
+		if len(ids) == 0 {
+			return updated, nil
+		}
+
+		n, err := c.sync(ctx, ids)
+		updated += n
+		if err != nil {
+			return updated, err
+		}
+		after = ids[len(ids)-1]
+	}
+}
+
+// Run сверяет счётчики раз в interval до отмены ctx
+func (c *commentCounts) Run(ctx context.Context, interval time.Duration) {
+	if c.baseURL == "" {
+		slog.Info("Comment counts sync is disabled: comments.url is not set")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		updated, err := c.syncRecent(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Failed to sync comment counts", "updated", updated, "error", err)
+		} else if updated > 0 {
+			slog.Info("Comment counts synced", "updated", updated)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncCommentsCount перезаписывает счётчик комментариев новости данными CommentService.
+// Вызывается API Gateway после добавления и удаления комментария; если вызов
+// не дошёл, счётчик исправит периодическая сверка.
+func (api *API) syncCommentsCount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["NewsID"])
+	if err != nil {
+		http.Error(w, "Invalid NewsID", http.StatusBadRequest)
+		return
+	}
+	if api.commentCounts.baseURL == "" {
+		http.Error(w, "Comment counts sync is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	counts, err := api.commentCounts.fetch(r.Context(), []int{id})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch comment count: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	var count int
+	err = api.db.QueryRow(r.Context(), `
+	UPDATE news SET comments_count = $2
+	WHERE id = $1
+	RETURNING comments_count;
+	`, id, counts[id]).Scan(&count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "News not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update comments count: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]int{"news_id": id, "comments_count": count}); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
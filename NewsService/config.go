@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"common/config"
+	"common/logging"
+	"common/postgres"
+	"common/tracing"
+)
+
+// Config - настройки сервиса новостей из файла config.json и переменных окружения.
+// Ленты из rss добавляются в таблицу sources при запуске, если их там ещё нет.
+//
+// Переменные окружения имеют приоритет над файлом:
+//
+//	NEWS_CONFIG             - путь к файлу конфигурации
+//	NEWS_LISTEN_ADDR        - адрес HTTP-сервера, например :8082
+//	NEWS_LOG_LEVEL - уровень журнала: debug, info, warn или error
+//	NEWS_DATABASE_DSN       - строка подключения к PostgreSQL
+//	NEWS_DB_MAX_CONNS       - максимальный размер пула соединений
+//	NEWS_DB_MIN_CONNS       - минимальный размер пула соединений
+//	NEWS_DB_CONNECT_TIMEOUT - таймаут подключения к БД, например 5s
+//	NEWS_DB_AUTO_MIGRATE    - применять миграции при запуске: true или false
+//	NEWS_READ_TIMEOUT, NEWS_WRITE_TIMEOUT, NEWS_IDLE_TIMEOUT - таймауты HTTP-сервера
+//	NEWS_SHUTDOWN_TIMEOUT - время на завершение текущих запросов при остановке
+//	NEWS_COMMENTS_URL       - адрес CommentService для синхронизации счётчиков комментариев
+//	NEWS_COMMENTS_SYNC_INTERVAL - период сверки счётчиков, например 10m
+//	NEWS_COMMENTS_SYNC_WINDOW - сверяются только новости, опубликованные за это время, например 168h
+//	NEWS_INTERNAL_TOKEN     - токен внутренних маршрутов, которые вызывает API Gateway
+//	                          (Authorization: Bearer <token>); без него эти маршруты отключены
+//	NEWS_ADMIN_TOKEN        - токен добавления, изменения и удаления источников /sources
+//	                          (Authorization: Bearer <token>); без него источники меняются
+//	                          только через rss в конфигурации, а маршруты отвечают 403
+//	NEWS_TRACING_EXPORTER - экспорт трассировки: none, stdout (спаны пишутся в stderr) или otlp
+//	NEWS_TRACING_ENDPOINT - адрес приёма спанов OTLP/HTTP, например http://localhost:4318/v1/traces
+//	NEWS_TRACING_SAMPLE_RATIO - доля трассируемых запросов от 0 до 1
+type Config struct {
+	Listen         string          `json:"listen"`
+	LogLevel       string          `json:"log_level"`
+	Database       postgres.Config `json:"database"`
+	Server         config.Server   `json:"server"`
+	Tracing        tracing.Config  `json:"tracing"`
+	Comments       CommentsConfig  `json:"comments"`
+	InternalToken  string          `json:"internal_token"`
+	AdminToken     string          `json:"admin_token"`     // токен правки /sources; лучше задавать через NEWS_ADMIN_TOKEN
+	RSS            []string        `json:"rss"`             // список RSS/Atom лент
+	RequestPeriod  int             `json:"request_period"`  // период опроса лент в минутах
+	SearchLanguage string          `json:"search_language"` // язык полнотекстового поиска: russian или english
+}
+
+// CommentsConfig - источник счётчиков комментариев для sort=comments
+type CommentsConfig struct {
+	URL          string          `json:"url"`           // базовый адрес CommentService; пустой отключает синхронизацию
+	SyncInterval config.Duration `json:"sync_interval"` // период сверки счётчиков
+	// Сверяются только новости не старше SyncWindow: комментарии пишут в основном к свежим
+	// новостям, а счётчики старых обновляет API Gateway после каждого комментария
+	SyncWindow config.Duration `json:"sync_window"`
+}
+
+const configPath = "config.json"
+
+// loadConfig читает конфигурацию из файла и переменных окружения и проверяет её.
+// Файл по умолчанию может отсутствовать, если всё задано переменными окружения.
+func loadConfig() (Config, error) {
+	cfg := Config{
+		Listen:   ":8082",
+		LogLevel: "info",
+		Database: postgres.DefaultConfig(),
+		Server:   config.DefaultServer(),
+		Tracing:  tracing.DefaultConfig(),
+		Comments: CommentsConfig{
+			URL:          "http://localhost:8081",
+			SyncInterval: config.Duration(10 * time.Minute),
+			SyncWindow:   config.Duration(7 * 24 * time.Hour),
+		},
+		RequestPeriod:  defaultPollInterval,
+		SearchLanguage: defaultSearchLanguage,
+	}
+
+	if err := config.Load("NEWS_CONFIG", configPath, &cfg); err != nil {
+		return cfg, err
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// applyEnv переопределяет настройки значениями из переменных окружения
+func (cfg *Config) applyEnv() error {
+	// Пустой NEWS_COMMENTS_URL отключает синхронизацию счётчиков
+	if value, ok := os.LookupEnv("NEWS_COMMENTS_URL"); ok {
+		cfg.Comments.URL = value
+	}
+	return config.ApplyEnv(cfg.Server.Env("NEWS_"), cfg.Tracing.Env("NEWS_"), cfg.Database.Env("NEWS_"), map[string]any{
+		"NEWS_LISTEN_ADDR":            &cfg.Listen,
+		"NEWS_LOG_LEVEL":              &cfg.LogLevel,
+		"NEWS_INTERNAL_TOKEN":         &cfg.InternalToken,
+		"NEWS_ADMIN_TOKEN":            &cfg.AdminToken,
+		"NEWS_COMMENTS_SYNC_INTERVAL": &cfg.Comments.SyncInterval,
+		"NEWS_COMMENTS_SYNC_WINDOW":   &cfg.Comments.SyncWindow,
+	})
+}
+
+func (cfg *Config) validate() error {
+	if cfg.Listen == "" {
+		return errors.New("listen address is required (listen or NEWS_LISTEN_ADDR)")
+	}
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		return fmt.Errorf("invalid log_level %q: must be debug, info, warn or error", cfg.LogLevel)
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		return err
+	}
+
+	if err := config.Positive(cfg.Server.Durations(), cfg.Database.Durations(), map[string]config.Duration{
+		"comments.sync_interval": cfg.Comments.SyncInterval,
+		"comments.sync_window":   cfg.Comments.SyncWindow,
+	}); err != nil {
+		return err
+	}
+
+	if cfg.RequestPeriod <= 0 || cfg.RequestPeriod > maxPollInterval {
+		return fmt.Errorf("request_period must be between 1 and %d minutes", maxPollInterval)
+	}
+	lang, ok := searchLanguage(cfg.SearchLanguage)
+	if !ok {
+		return fmt.Errorf("unsupported search_language %q", cfg.SearchLanguage)
+	}
+	cfg.SearchLanguage = lang
+
+	if cfg.Comments.URL != "" {
+		cfg.Comments.URL = strings.TrimRight(cfg.Comments.URL, "/")
+		if err := config.CheckURL("comments.url", cfg.Comments.URL); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.Tracing.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// newsCursor - позиция в списке новостей для постраничного вывода по ключу
+// (значение поля сортировки, id). Клиенту передаётся в виде непрозрачной строки.
+type newsCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Key   string `json:"k"` // значение поля сортировки в текстовом представлении PostgreSQL
+	ID    int    `json:"id"`
+	Query string `json:"q,omitempty"` // для sort=relevance: отпечаток поискового запроса, см. queryFingerprint
+}
+
+// queryFingerprint возвращает короткий хеш поискового запроса и языка. Ранг
+// relevance зависит от запроса, поэтому курсор с другим s указывал бы на
+// случайное место в выдаче.
+func queryFingerprint(lang, search string) string {
+	sum := sha256.Sum256([]byte(lang + "\n" + search))
+	return base64.RawURLEncoding.EncodeToString(sum[:9])
+}
+
+func (c newsCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeNewsCursor(s string) (newsCursor, error) {
+	var c newsCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return c, errors.New("invalid cursor")
+	}
+	if _, ok := newsSorts[c.Sort]; !ok || (c.Order != "asc" && c.Order != "desc") {
+		return c, errors.New("invalid cursor")
+	}
+
+	return c, nil
+}
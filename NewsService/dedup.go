@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Параметры запроса, которые не влияют на содержимое страницы
+var trackingParams = map[string]bool{
+	"fbclid": true,
+	"gclid":  true,
+	"yclid":  true,
+	"ref":    true,
+}
+
+// canonicalURL приводит ссылку на публикацию к единому виду: без схемы http/https,
+// www, фрагмента, трекинговых параметров и завершающего слэша
+func canonicalURL(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	path := strings.TrimRight(u.EscapedPath(), "/")
+
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		if strings.HasPrefix(k, "utm_") || trackingParams[k] {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(host)
+	b.WriteString(path)
+	for i, k := range keys {
+		if i == 0 {
+			b.WriteByte('?')
+		} else {
+			b.WriteByte('&')
+		}
+		b.WriteString(url.QueryEscape(k))
+		b.WriteByte('=')
+		b.WriteString(url.QueryEscape(query.Get(k)))
+	}
+
+	return b.String()
+}
+
+// fingerprint - отпечаток публикации по заголовку и тексту без учёта регистра,
+// пунктуации и пробелов
+func fingerprint(title, content string) string {
+	h := sha256.Sum256([]byte(normalizeForFingerprint(title) + "\n" + normalizeForFingerprint(content)))
+	return hex.EncodeToString(h[:])
+}
+
+func normalizeForFingerprint(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// storeItem сохраняет публикацию или, если такая уже есть (по ссылке, отпечатку или
+// GUID в этом же источнике), привязывает к ней источник. GUID сравнивается только
+// в пределах источника: разные ленты могут использовать одинаковые GUID.
+// Возвращает true, если добавлена новая запись.
+func (ing *Ingester) storeItem(ctx context.Context, sourceID int, item FeedItem) (bool, error) {
+	link := canonicalURL(item.Link)
+	fp := fingerprint(item.Title, item.Content)
+
+	tx, err := ing.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var newsID int
+	created := false
+	err = tx.QueryRow(ctx, `
+	WITH by_guid AS (
+		SELECT news_id FROM news_sources WHERE source_id = $4 AND guid = NULLIF($2, '')
+	)
+	SELECT id FROM news
+	WHERE url = NULLIF($1, '') OR fingerprint = $3 OR id IN (SELECT news_id FROM by_guid)
+	-- запись, уже связанная с этим GUID источника, предпочтительнее: иначе GUID
+	-- оказался бы привязан к двум публикациям одного источника
+	ORDER BY id IN (SELECT news_id FROM by_guid) DESC, id
+	LIMIT 1;
+	`, link, item.GUID, fp, sourceID).Scan(&newsID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Публикация без даты получает время первого появления. Повторы ищутся только
+		// по ссылке, GUID и отпечатку, поэтому при следующих опросах это время не меняется.
+		createdAt := item.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now().UTC()
+		}
+		err = tx.QueryRow(ctx, `
+		INSERT INTO news (title, author, content, created_at, source_id, url, guid, fingerprint)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8)
+		RETURNING id;
+		`, item.Title, item.Author, item.Content, createdAt, sourceID, link, item.GUID, fp).Scan(&newsID)
+		created = true
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to store news: %v", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+	INSERT INTO news_sources (news_id, source_id, url, guid)
+	VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+	ON CONFLICT (news_id, source_id) DO UPDATE
+	SET guid = COALESCE(news_sources.guid, EXCLUDED.guid);
+	`, newsID, sourceID, item.Link, item.GUID)
+	if err != nil {
+		return false, fmt.Errorf("failed to link news to source: %v", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return created, nil
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// FeedItem - публикация из ленты, приведённая к полям таблицы news
+type FeedItem struct {
+	Title     string
+	Author    string
+	Content   string
+	Link      string
+	GUID      string
+	CreatedAt time.Time // нулевое значение, если в ленте нет распознаваемой даты
+}
+
+// Структуры RSS 2.0
+type rssFeed struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	Title          string `xml:"title"`
+	Link           string `xml:"link"`
+	GUID           string `xml:"guid"`
+	Author         string `xml:"author"`
+	Creator        string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Description    string `xml:"description"`
+	ContentEncoded string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate        string `xml:"pubDate"`
+}
+
+// Структуры Atom
+type atomFeed struct {
+	Title   string      `xml:"title"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Author    atomAuthor `xml:"author"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+// parseFeed разбирает RSS 2.0 или Atom ленту в зависимости от корневого элемента
+func parseFeed(data []byte) ([]FeedItem, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid feed: %v", err)
+	}
+
+	switch root.XMLName.Local {
+	case "rss":
+		return parseRSS(data)
+	case "feed":
+		return parseAtom(data)
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root.XMLName.Local)
+	}
+}
+
+func parseRSS(data []byte) ([]FeedItem, error) {
+	var feed rssFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, fmt.Errorf("invalid rss feed: %v", err)
+	}
+
+	items := make([]FeedItem, 0, len(feed.Channel.Items))
+	for _, it := range feed.Channel.Items {
+		content := it.ContentEncoded
+		if content == "" {
+			content = it.Description
+		}
+		author := firstNonEmpty(it.Creator, it.Author, feed.Channel.Title)
+
+		items = append(items, FeedItem{
+			Title:     cleanText(it.Title),
+			Author:    cleanText(author),
+			Content:   cleanText(content),
+			Link:      strings.TrimSpace(it.Link),
+			GUID:      strings.TrimSpace(it.GUID),
+			CreatedAt: parseFeedTime(it.PubDate),
+		})
+	}
+
+	return items, nil
+}
+
+func parseAtom(data []byte) ([]FeedItem, error) {
+	var feed atomFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, fmt.Errorf("invalid atom feed: %v", err)
+	}
+
+	items := make([]FeedItem, 0, len(feed.Entries))
+	for _, e := range feed.Entries {
+		content := e.Content
+		if content == "" {
+			content = e.Summary
+		}
+		author := firstNonEmpty(e.Author.Name, feed.Author.Name, feed.Title)
+
+		var link string
+		for _, l := range e.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				link = l.Href
+				break
+			}
+		}
+
+		items = append(items, FeedItem{
+			Title:     cleanText(e.Title),
+			Author:    cleanText(author),
+			Content:   cleanText(content),
+			Link:      strings.TrimSpace(link),
+			GUID:      strings.TrimSpace(e.ID),
+			CreatedAt: parseFeedTime(firstNonEmpty(e.Published, e.Updated)),
+		})
+	}
+
+	return items, nil
+}
+
+var feedTimeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+}
+
+// parseFeedTime разбирает дату публикации. Если формат не распознан, возвращается
+// нулевое время: дату публикации тогда заменяет время первого появления в ленте,
+// которое записывается один раз при добавлении.
+func parseFeedTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
+
+// cleanText убирает HTML-разметку и лишние пробелы
+func cleanText(s string) string {
+	s = htmlTagRe.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Ingester периодически опрашивает включённые источники из таблицы sources
+// и сохраняет публикации в БД
+type Ingester struct {
+	db         *pgxpool.Pool
+	client     *http.Client
+	tick       time.Duration // как часто проверять, не пора ли опросить источник
+	maxBackoff time.Duration // верхняя граница задержки для неработающих лент
+}
+
+func NewIngester(db *pgxpool.Pool) *Ingester {
+	return &Ingester{
+		db:         db,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		tick:       time.Minute,
+		maxBackoff: 6 * time.Hour,
+	}
+}
+
+// feedState - источник вместе с сохранёнными валидаторами кэша и счётчиком ошибок
+type feedState struct {
+	Source
+	ETag                string
+	LastModified        string
+	ConsecutiveFailures int
+}
+
+// fetchResult - результат одного HTTP-запроса к ленте
+type fetchResult struct {
+	Status       int
+	Body         []byte
+	ETag         string
+	LastModified string
+}
+
+// NotModified сообщает, что лента не изменилась с прошлого опроса
+func (res fetchResult) NotModified() bool {
+	return res.Status == http.StatusNotModified
+}
+
+// Run опрашивает источники, у которых подошло время следующего опроса, пока не отменён ctx
+func (ing *Ingester) Run(ctx context.Context) {
+	ticker := time.NewTicker(ing.tick)
+	defer ticker.Stop()
+
+	for {
+		feeds, err := ing.dueFeeds(ctx)
+		if err != nil {
+			slog.Error("Failed to load sources", "error", err)
+		}
+
+		for _, feed := range feeds {
+			n, err := ing.poll(ctx, feed)
+			if err != nil {
+				slog.Warn("Failed to poll feed", "source_id", feed.ID, "feed", feed.URL, "error", err)
+				continue
+			}
+			slog.Info("Feed polled", "source_id", feed.ID, "feed", feed.URL, "new_items", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dueFeeds возвращает включённые источники, которые пора опросить
+func (ing *Ingester) dueFeeds(ctx context.Context) ([]feedState, error) {
+	rows, err := ing.db.Query(ctx, `
+	SELECT id, name, url, poll_interval, enabled, created_at,
+		COALESCE(etag, ''), COALESCE(last_modified, ''), consecutive_failures
+	FROM sources
+	WHERE enabled AND (next_poll_at IS NULL OR next_poll_at <= $1)
+	ORDER BY id;
+	`, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feeds []feedState
+	for rows.Next() {
+		var f feedState
+		if err := rows.Scan(&f.ID, &f.Name, &f.URL, &f.PollInterval, &f.Enabled, &f.CreatedAt,
+			&f.ETag, &f.LastModified, &f.ConsecutiveFailures); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+
+	return feeds, rows.Err()
+}
+
+// poll загружает ленту источника, сохраняет публикации и записывает результат
+// опроса. Возвращает количество добавленных публикаций.
+//
+// Отсрочку следующего опроса увеличивают только ошибки загрузки и разбора ленты.
+// Если не удалось сохранить публикации, лента исправна: результат опроса не
+// записывается, и источник будет опрошен заново на следующем такте.
+func (ing *Ingester) poll(ctx context.Context, feed feedState) (int, error) {
+	var items []FeedItem
+	res, err := ing.fetch(ctx, feed)
+	if err == nil && !res.NotModified() {
+		items, err = parseFeed(res.Body)
+	}
+	if err != nil {
+		if rerr := ing.recordFetch(ctx, feed, res, err); rerr != nil {
+			slog.Error("Failed to save fetch status", "source_id", feed.ID, "error", rerr)
+		}
+		return 0, err
+	}
+
+	added, err := ing.store(ctx, feed.ID, items)
+	if err != nil {
+		return added, fmt.Errorf("failed to store items: %v", err)
+	}
+	return added, ing.recordFetch(ctx, feed, res, nil)
+}
+
+// Максимальный размер ленты. Ленты приходят с чужих серверов, и без предела
+// один источник мог бы занять всю память сервиса.
+const maxFeedSize = 5 << 20
+
+// fetch выполняет условный GET-запрос с If-None-Match/If-Modified-Since.
+// Ответы кроме 200 и 304 считаются ошибкой.
+func (ing *Ingester) fetch(ctx context.Context, feed feedState) (fetchResult, error) {
+	var res fetchResult
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.URL, nil)
+	if err != nil {
+		return res, err
+	}
+	if feed.ETag != "" {
+		req.Header.Set("If-None-Match", feed.ETag)
+	}
+	if feed.LastModified != "" {
+		req.Header.Set("If-Modified-Since", feed.LastModified)
+	}
+
+	resp, err := ing.client.Do(req)
+	if err != nil {
+		return res, fmt.Errorf("failed to fetch feed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	res.Status = resp.StatusCode
+	res.ETag = resp.Header.Get("ETag")
+	res.LastModified = resp.Header.Get("Last-Modified")
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		// сервер может не повторять валидаторы в ответе 304
+		res.ETag = firstNonEmpty(res.ETag, feed.ETag)
+		res.LastModified = firstNonEmpty(res.LastModified, feed.LastModified)
+		return res, nil
+	default:
+		return res, fmt.Errorf("failed to fetch feed: %s", resp.Status)
+	}
+
+	// Лента читается не больше maxFeedSize: лишний байт показывает, что предел превышен
+	res.Body, err = io.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+	if err != nil {
+		return res, fmt.Errorf("error reading feed body: %v", err)
+	}
+	if len(res.Body) > maxFeedSize {
+		res.Body = nil
+		return res, fmt.Errorf("feed is larger than %d MiB", maxFeedSize>>20)
+	}
+
+	return res, nil
+}
+
+// nextPollDelay возвращает задержку до следующего опроса: обычный интервал
+// источника или, после failures ошибок подряд, экспоненциально увеличенный интервал.
+// maxBackoff ограничивает только рост отсрочки: источник с интервалом больше
+// maxBackoff опрашивается со своим интервалом.
+func (ing *Ingester) nextPollDelay(pollInterval int, failures int) time.Duration {
+	interval := time.Duration(pollInterval) * time.Minute
+	if failures == 0 || interval >= ing.maxBackoff {
+		return interval
+	}
+	delay := interval
+	for i := 0; i < failures && delay < ing.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > ing.maxBackoff {
+		delay = ing.maxBackoff
+	}
+	return delay
+}
+
+// backoff возвращает число ошибок подряд после опроса с результатом fetchErr
+// и задержку до следующего опроса: ошибка увеличивает отсрочку, успешный опрос сбрасывает её
+func (ing *Ingester) backoff(feed feedState, fetchErr error) (int, time.Duration) {
+	failures := 0
+	if fetchErr != nil {
+		failures = feed.ConsecutiveFailures + 1
+	}
+	return failures, ing.nextPollDelay(feed.PollInterval, failures)
+}
+
+// recordFetch сохраняет результат опроса и планирует следующий опрос источника
+func (ing *Ingester) recordFetch(ctx context.Context, feed feedState, res fetchResult, fetchErr error) error {
+	now := time.Now().UTC()
+	failures, delay := ing.backoff(feed, fetchErr)
+
+	if fetchErr != nil {
+		_, err := ing.db.Exec(ctx, `
+		UPDATE sources
+		SET last_status = NULLIF($2, 0), last_error = $3, last_fetched_at = $4,
+			consecutive_failures = $5, next_poll_at = $6
+		WHERE id = $1;
+		`, feed.ID, res.Status, fetchErr.Error(), now, failures, now.Add(delay))
+		return err
+	}
+
+	_, err := ing.db.Exec(ctx, `
+	UPDATE sources
+	SET last_status = $2, last_error = NULL, last_fetched_at = $3, last_success_at = $3,
+		consecutive_failures = 0, next_poll_at = $4,
+		etag = NULLIF($5, ''), last_modified = NULLIF($6, '')
+	WHERE id = $1;
+	`, feed.ID, res.Status, now, now.Add(delay), res.ETag, res.LastModified)
+	return err
+}
+
+// store сохраняет публикации ленты, объединяя повторы одной и той же истории
+func (ing *Ingester) store(ctx context.Context, sourceID int, items []FeedItem) (int, error) {
+	added := 0
+	for _, item := range items {
+		if item.Title == "" {
+			continue
+		}
+
+		created, err := ing.storeItem(ctx, sourceID, item)
+		if err != nil {
+			return added, err
+		}
+		if created {
+			added++
+		}
+	}
+
+	return added, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseFeedDates(t *testing.T) {
+	const rss = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Лента</title>
+<item><title>С датой</title><link>https://example.com/a</link><pubDate>Mon, 02 Jan 2006 15:04:05 +0300</pubDate></item>
+<item><title>Без даты</title><link>https://example.com/b</link></item>
+<item><title>Дата не разбирается</title><link>https://example.com/c</link><pubDate>вчера</pubDate></item>
+</channel></rss>`
+
+	items, err := parseFeed([]byte(rss))
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("got %d items, want 3", len(items))
+	}
+
+	want := time.Date(2006, 1, 2, 12, 4, 5, 0, time.UTC)
+	if !items[0].CreatedAt.Equal(want) {
+		t.Errorf("dated item: CreatedAt = %v, want %v", items[0].CreatedAt, want)
+	}
+	// Время первого появления подставляется только при добавлении в БД,
+	// чтобы при повторных опросах дата публикации не менялась
+	for _, item := range items[1:] {
+		if !item.CreatedAt.IsZero() {
+			t.Errorf("%q: CreatedAt = %v, want zero time", item.Title, item.CreatedAt)
+		}
+	}
+}
+
+func TestFetchConditionalGet(t *testing.T) {
+	const (
+		etag         = `"v1"`
+		lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	)
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			// Валидаторы в ответе 304 не повторяются
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte("<rss/>"))
+	}))
+	defer srv.Close()
+
+	ing := &Ingester{client: srv.Client()}
+	feed := feedState{Source: Source{URL: srv.URL}}
+
+	res, err := ing.fetch(context.Background(), feed)
+	if err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+	if res.NotModified() || string(res.Body) != "<rss/>" {
+		t.Fatalf("first fetch: status %d, body %q", res.Status, res.Body)
+	}
+	if res.ETag != etag || res.LastModified != lastModified {
+		t.Fatalf("first fetch: validators %q, %q", res.ETag, res.LastModified)
+	}
+
+	feed.ETag, feed.LastModified = res.ETag, res.LastModified
+	res, err = ing.fetch(context.Background(), feed)
+	if err != nil {
+		t.Fatalf("conditional fetch: %v", err)
+	}
+	if !res.NotModified() || res.Body != nil {
+		t.Fatalf("conditional fetch: status %d, body %q, want 304 without body", res.Status, res.Body)
+	}
+	if res.ETag != etag || res.LastModified != lastModified {
+		t.Errorf("conditional fetch: validators %q, %q, want the stored ones", res.ETag, res.LastModified)
+	}
+	if requests != 2 {
+		t.Errorf("server got %d requests, want 2", requests)
+	}
+}
+
+func TestFetchErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ing := &Ingester{client: srv.Client()}
+	res, err := ing.fetch(context.Background(), feedState{Source: Source{URL: srv.URL}})
+	if err == nil {
+		t.Fatal("fetch: want error for 503")
+	}
+	if res.Status != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", res.Status)
+	}
+}
+
+func TestFetchTooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<rss>"))
+		w.Write(bytes.Repeat([]byte(" "), maxFeedSize))
+	}))
+	defer srv.Close()
+
+	ing := &Ingester{client: srv.Client()}
+	res, err := ing.fetch(context.Background(), feedState{Source: Source{URL: srv.URL}})
+	if err == nil {
+		t.Fatal("fetch: want error for a feed over maxFeedSize")
+	}
+	if res.Body != nil {
+		t.Errorf("got %d bytes of body, want none", len(res.Body))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	ing := &Ingester{maxBackoff: time.Hour}
+	feed := feedState{Source: Source{PollInterval: 5}}
+	fetchErr := errors.New("failed to fetch feed: 503 Service Unavailable")
+
+	// Каждая ошибка подряд удваивает задержку до max_backoff
+	for i, want := range []time.Duration{10 * time.Minute, 20 * time.Minute, 40 * time.Minute, time.Hour, time.Hour} {
+		failures, delay := ing.backoff(feed, fetchErr)
+		if failures != i+1 || delay != want {
+			t.Fatalf("failure %d: got %d failures and %v delay, want %d and %v", i+1, failures, delay, i+1, want)
+		}
+		feed.ConsecutiveFailures = failures
+	}
+
+	// Успешный опрос сбрасывает счётчик и возвращает обычный интервал
+	failures, delay := ing.backoff(feed, nil)
+	if failures != 0 || delay != 5*time.Minute {
+		t.Errorf("after success: got %d failures and %v delay, want 0 and 5m", failures, delay)
+	}
+
+	// Интервал реже max_backoff не сокращается ни без ошибок, ни после них
+	daily := feedState{Source: Source{PollInterval: 24 * 60}}
+	for _, fetchErr := range []error{nil, fetchErr} {
+		if _, delay := ing.backoff(daily, fetchErr); delay != 24*time.Hour {
+			t.Errorf("daily source, error %v: delay %v, want 24h", fetchErr, delay)
+		}
+	}
+}
@@ -3,14 +3,25 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
-	"strconv"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"common/logging"
+	"common/middleware"
+	"common/postgres"
+	"common/tracing"
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
 type NewsFullDetailed struct {
@@ -18,33 +29,55 @@ type NewsFullDetailed struct {
 	Title     string    `json:"title"`
 	Author    string    `json:"author"`
 	Content   string    `json:"content"`
+	Source    string    `json:"source"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 type NewsShortDetailed struct {
-	ID        int       `json:"id"`
-	Title     string    `json:"title"`
-	Author    string    `json:"author"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           int       `json:"id"`
+	Title        string    `json:"title"`
+	Author       string    `json:"author"`
+	Source       string    `json:"source"`
+	SourcesCount int       `json:"sources_count"` // в скольких источниках вышла публикация
+	CreatedAt    time.Time `json:"created_at"`
+	Snippet      string    `json:"snippet,omitempty"` // фрагмент текста с подсветкой найденных слов
 }
 
+// Pagination - сведения о странице при выводе по номеру страницы
 type Pagination struct {
-	TotalPages  int `json:"totalPages"`
-	CurrentPage int `json:"currentPage"`
-	PageSize    int `json:"pageSize"`
+	TotalPages  int    `json:"totalPages"`
+	CurrentPage int    `json:"currentPage"`
+	PageSize    int    `json:"pageSize"`
+	NextCursor  string `json:"next_cursor,omitempty"` // курсор следующей страницы, если она есть
 }
 
-type API struct {
-	r  *mux.Router // маршрутизатор запросов
-	db *pgxpool.Pool
+// CursorPagination - сведения о странице в режиме курсора: общее количество
+// и номер страницы в этом режиме не считаются
+type CursorPagination struct {
+	PageSize   int    `json:"pageSize"`
+	Cursor     string `json:"cursor"`                // курсор текущей страницы
+	NextCursor string `json:"next_cursor,omitempty"` // курсор следующей страницы, если она есть
 }
 
-const pageSize = 15
+type API struct {
+	r          *mux.Router // маршрутизатор запросов
+	db         *pgxpool.Pool
+	searchLang string // язык полнотекстового поиска по умолчанию
+
+	commentCounts *commentCounts
+	internalToken string // токен маршрутов, которые вызывает только API Gateway
+	adminToken    string // токен маршрутов правки источников
+}
 
-func NewAPI(db *pgxpool.Pool) *API {
+func NewAPI(db *pgxpool.Pool, cfg Config) *API {
 	api := &API{
-		r:  mux.NewRouter(),
-		db: db,
+		r:          mux.NewRouter(),
+		db:         db,
+		searchLang: cfg.SearchLanguage,
+
+		commentCounts: newCommentCounts(db, cfg.Comments),
+		internalToken: cfg.InternalToken,
+		adminToken:    cfg.AdminToken,
 	}
 	api.endpoints() // Настройка маршрутов
 	return api
@@ -56,21 +89,19 @@ func (api *API) Router() *mux.Router {
 
 func (api *API) endpoints() {
 	// Обработчики для различных маршрутов
+	api.r.HandleFunc("/healthz", postgres.Healthz).Methods(http.MethodGet)
+	api.r.HandleFunc("/readyz", postgres.Readyz(api.db)).Methods(http.MethodGet)
+	api.r.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
 	api.r.HandleFunc("/news", api.getNews).Methods(http.MethodGet)
 	api.r.HandleFunc("/news/{NewsID}", api.getSoloNews).Methods((http.MethodGet))
-}
-
-func initDB() *pgxpool.Pool {
-	var err error
-
-	connString := "host=localhost port=5432 user=postgres password=postgres dbname=NewsService sslmode=disable"
-
-	db, err := pgxpool.Connect(context.Background(), connString)
-	if err != nil {
-		log.Fatalf("Unable to connect to database: %v\n", err)
-	}
-
-	return db
+	api.r.HandleFunc("/news/{NewsID}/comments_count/sync", middleware.RequireToken(api.internalToken, api.syncCommentsCount)).Methods(http.MethodPost)
+
+	api.r.HandleFunc("/sources", api.getSources).Methods(http.MethodGet)
+	api.r.HandleFunc("/sources", middleware.RequireToken(api.adminToken, api.addSource)).Methods(http.MethodPost)
+	api.r.HandleFunc("/sources/{SourceID}", api.getSource).Methods(http.MethodGet)
+	api.r.HandleFunc("/sources/{SourceID}", middleware.RequireToken(api.adminToken, api.updateSource)).Methods(http.MethodPut)
+	api.r.HandleFunc("/sources/{SourceID}", middleware.RequireToken(api.adminToken, api.deleteSource)).Methods(http.MethodDelete)
+	api.r.HandleFunc("/sources/{SourceID}/health", api.getSourceHealth).Methods(http.MethodGet)
 }
 
 func (api *API) getSoloNews(w http.ResponseWriter, r *http.Request) {
@@ -79,10 +110,16 @@ func (api *API) getSoloNews(w http.ResponseWriter, r *http.Request) {
 
 	var news NewsFullDetailed
 
-	err := api.db.QueryRow(context.Background(), `
-	SELECT * FROM news
-	WHERE id = $1;
-	`, id).Scan(&news.ID, &news.Title, &news.Content, &news.Author, &news.CreatedAt)
+	err := api.db.QueryRow(r.Context(), `
+	SELECT n.id, n.title, n.author, n.content, COALESCE(s.name, ''), n.created_at
+	FROM news n
+	LEFT JOIN sources s ON s.id = n.source_id
+	WHERE n.id = $1;
+	`, id).Scan(&news.ID, &news.Title, &news.Author, &news.Content, &news.Source, &news.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "News not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to fetch news: %v", err), http.StatusInternalServerError)
 		return
@@ -97,44 +134,83 @@ func (api *API) getSoloNews(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// getNews возвращает страницу списка новостей. Поддерживаются два режима:
+// по номеру страницы (page) и по курсору (cursor). В режиме курсора общее количество
+// не считается, а следующая страница запрашивается по значению next_cursor из
+// предыдущего ответа.
 func (api *API) getNews(w http.ResponseWriter, r *http.Request) {
-	s := r.URL.Query().Get("s")
-	pageParam := r.URL.Query().Get("page")
-	if pageParam == "" {
-		pageParam = "1" // Если параметр не передан, то по умолчанию первая страница
+	q, apiErr := parseNewsQuery(r, api.searchLang)
+	if apiErr != nil {
+		writeError(w, http.StatusBadRequest, apiErr)
+		return
 	}
 
-	page, err := strconv.Atoi(pageParam)
-	if err != nil || page <= 0 {
-		http.Error(w, "Invalid page parameter", http.StatusBadRequest)
-		return
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
 	}
 
-	var totalCount int
-	err = api.db.QueryRow(context.Background(), `
-	SELECT COUNT(*) FROM news
-	WHERE title ILIKE $1;
-	`, "%"+s+"%").Scan(&totalCount)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to fetch total count: %v", err), http.StatusInternalServerError)
-		return
+	// Полнотекстовый поиск по заголовку и тексту
+	from, where, snippet := "", "TRUE", "''"
+	if q.Search != "" {
+		langArg := arg(q.Lang)
+		from = fmt.Sprintf("CROSS JOIN websearch_to_tsquery(%s::regconfig, %s) q", langArg, arg(q.Search))
+		where = "n.search_vector @@ q"
+		snippet = fmt.Sprintf("ts_headline(%s::regconfig, n.content, q, '%s')", langArg, headlineOptions)
 	}
 
-	totalPages := (totalCount + pageSize - 1) / pageSize
+	// Фильтры по автору и периоду публикации
+	if q.Author != "" {
+		where += fmt.Sprintf(" AND lower(n.author) = lower(%s)", arg(q.Author))
+	}
+	if q.From != nil {
+		where += fmt.Sprintf(" AND n.created_at >= %s", arg(*q.From))
+	}
+	if q.To != nil {
+		where += fmt.Sprintf(" AND n.created_at <= %s", arg(*q.To))
+	}
+
+	spec := newsSorts[q.Sort]
+	order := fmt.Sprintf("%s %s, n.id %s", spec.expr, q.Order, q.Order)
 
-	pagination := Pagination{
-		TotalPages:  totalPages,
-		CurrentPage: page,
-		PageSize:    pageSize,
+	pagination := Pagination{PageSize: q.Limit}
+	offset := 0
+	if q.Cursor != nil {
+		op := "<"
+		if q.Order == "asc" {
+			op = ">"
+		}
+		where += fmt.Sprintf(" AND (%s, n.id) %s (%s::%s, %s)", spec.expr, op, arg(q.Cursor.Key), spec.keyType, arg(q.Cursor.ID))
+	} else {
+		var totalCount int
+		err := api.db.QueryRow(r.Context(), fmt.Sprintf(`
+		SELECT COUNT(*) FROM news n
+		%s
+		WHERE %s;
+		`, from, where), args...).Scan(&totalCount)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to fetch total count: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		pagination.TotalPages = (totalCount + q.Limit - 1) / q.Limit
+		pagination.CurrentPage = q.Page
+		offset = (q.Page - 1) * q.Limit
 	}
 
-	offset := (page - 1) * pageSize
-	rows, err := api.db.Query(context.Background(), `
-		SELECT id,title, author, created_at FROM news
-		WHERE title ILIKE $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3;
-		`, "%"+s+"%", pageSize, offset)
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	rows, err := api.db.Query(r.Context(), fmt.Sprintf(`
+		SELECT n.id, n.title, n.author, COALESCE(s.name, ''),
+			(SELECT COUNT(*) FROM news_sources ns WHERE ns.news_id = n.id),
+			n.created_at, %s, (%s)::text
+		FROM news n
+		%s
+		LEFT JOIN sources s ON s.id = n.source_id
+		WHERE %s
+		ORDER BY %s
+		LIMIT %d OFFSET %d;
+		`, snippet, spec.expr, from, where, order, q.Limit+1, offset), args...)
 
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to fetch news: %v", err), http.StatusInternalServerError)
@@ -143,16 +219,29 @@ func (api *API) getNews(w http.ResponseWriter, r *http.Request) {
 	defer rows.Close()
 
 	var news []NewsShortDetailed
+	var sortKeys []string
 	for rows.Next() {
 		var soloNews NewsShortDetailed
+		var sortKey string
 
 		// Чтение данных из строки
-		if err := rows.Scan(&soloNews.ID, &soloNews.Title, &soloNews.Author, &soloNews.CreatedAt); err != nil {
+		if err := rows.Scan(&soloNews.ID, &soloNews.Title, &soloNews.Author, &soloNews.Source, &soloNews.SourcesCount, &soloNews.CreatedAt, &soloNews.Snippet, &sortKey); err != nil {
 			http.Error(w, fmt.Sprintf("Error scanning news: %v", err), http.StatusInternalServerError)
 			return
 		}
 
 		news = append(news, soloNews)
+		sortKeys = append(sortKeys, sortKey)
+	}
+
+	if len(news) > q.Limit {
+		news = news[:q.Limit]
+		last := len(news) - 1
+		next := newsCursor{Sort: q.Sort, Order: q.Order, Key: sortKeys[last], ID: news[last].ID}
+		if q.Sort == "relevance" {
+			next.Query = queryFingerprint(q.Lang, q.Search)
+		}
+		pagination.NextCursor = next.Encode()
 	}
 
 	response := struct {
@@ -162,6 +251,13 @@ func (api *API) getNews(w http.ResponseWriter, r *http.Request) {
 		News:       news,
 		Pagination: pagination,
 	}
+	if q.Cursor != nil {
+		response.Pagination = CursorPagination{
+			PageSize:   pagination.PageSize,
+			Cursor:     q.Cursor.Encode(),
+			NextCursor: pagination.NextCursor,
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -169,55 +265,82 @@ func (api *API) getNews(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func generateRequestID() string {
-	return fmt.Sprintf("%d", time.Now().UnixNano())
-}
+func main() {
+	cfg, err := loadConfig()
+	logging.Setup(cfg.LogLevel, "news")
+	if err != nil {
+		logging.Fatal("Failed to load config", "error", err)
+	}
 
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-// WriteHeader записывает HTTP-статус и сохраняет его в переменную
-func (rw *responseWriter) WriteHeader(statusCode int) {
-	rw.statusCode = statusCode
-	rw.ResponseWriter.WriteHeader(statusCode)
-}
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, "news")
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
 
-// Write записывает тело ответа
-func (rw *responseWriter) Write(p []byte) (n int, err error) {
-	return rw.ResponseWriter.Write(p)
-}
+	db, err := postgres.Connect(ctx, cfg.Database, cfg.Tracing.Enabled())
+	if err != nil {
+		logging.Fatal("Unable to connect to database", "error", err)
+	}
+	defer db.Close()
 
-func HeadersMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := r.URL.Query().Get("request_id")
-		if requestID == "" {
-			requestID = generateRequestID()
+	// Подкоманда migrate up|down [N]|status выполняется вместо запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrations.Run(ctx, db, os.Args[2:]); err != nil {
+			logging.Fatal("Migration failed", "error", err)
 		}
+		return
+	}
+	prometheus.MustRegister(postgres.NewPoolCollector(db))
 
-		wrappedWriter := &responseWriter{ResponseWriter: w}
-
-		next.ServeHTTP(wrappedWriter, r)
+	if cfg.Database.AutoMigrate {
+		if err := migrations.Up(ctx, db); err != nil {
+			logging.Fatal("Failed to apply migrations", "error", err)
+		}
+	}
 
-		log.Printf(
-			"Request ID: %s | Time: %s | IP: %s | Status: %d",
-			requestID,
-			time.Now().Format(time.RFC3339),
-			r.RemoteAddr,
-			wrappedWriter.statusCode,
-		)
+	// Ленты из конфигурации добавляются к источникам в БД
+	if err := seedSources(ctx, db, cfg); err != nil {
+		slog.Error("Failed to seed sources", "error", err)
+	}
 
-	})
-}
+	// Запуск сбора новостей из RSS/Atom лент
+	go NewIngester(db).Run(ctx)
 
-func main() {
-	db := initDB()
-	defer db.Close()
-
-	api := NewAPI(db)
-	api.Router().Use(HeadersMiddleware)
-	http.Handle("/", api.Router())
-	fmt.Println("Server started at http://localhost:8082/")
-	log.Fatal(http.ListenAndServe(":8082", api.r))
+	if cfg.AdminToken == "" {
+		slog.Warn("Admin token is not set, changing /sources is disabled")
+	}
+	api := NewAPI(db, cfg)
+	// Периодическая сверка исправляет счётчики, обновление которых после
+	// добавления или удаления комментария не дошло
+	go api.commentCounts.Run(ctx, time.Duration(cfg.Comments.SyncInterval))
+	middleware.Use(api.Router(), otelmux.Middleware("news"))
+	srv := &http.Server{
+		Addr:         cfg.Listen,
+		Handler:      api.Router(),
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout),
+	}
+	go func() {
+		slog.Info("Server started", "addr", cfg.Listen)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("Server failed", "error", err)
+		}
+	}()
+
+	// По SIGINT или SIGTERM сервер перестаёт принимать соединения и ждёт
+	// завершения текущих запросов не дольше shutdown_timeout
+	<-ctx.Done()
+	slog.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Graceful shutdown failed", "error", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
 }
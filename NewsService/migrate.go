@@ -0,0 +1,18 @@
+package main
+
+import (
+	"embed"
+
+	"common/migrate"
+)
+
+// Миграции схемы БД: файлы NNNN_name.up.sql и NNNN_name.down.sql. Применённые
+// версии записываются в таблицу schema_migrations.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Ключ pg_advisory_lock, чтобы несколько экземпляров сервиса не применяли миграции одновременно
+const migrationLockKey = 8082
+
+var migrations = migrate.New(migrationFiles, migrationLockKey)
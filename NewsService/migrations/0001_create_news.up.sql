@@ -0,0 +1,9 @@
+-- Исходная схема; IF NOT EXISTS позволяет применить миграцию к базе,
+-- созданной вручную из news_create.sql
+CREATE TABLE IF NOT EXISTS news (
+    id SERIAL PRIMARY KEY,
+    title TEXT NOT NULL,
+    author VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS news_sources;
+
+ALTER TABLE news
+    DROP COLUMN IF EXISTS fingerprint,
+    DROP COLUMN IF EXISTS guid,
+    DROP COLUMN IF EXISTS url,
+    DROP COLUMN IF EXISTS source_id;
+
+DROP TABLE IF EXISTS sources;
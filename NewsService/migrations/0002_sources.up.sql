@@ -0,0 +1,38 @@
+-- Источники публикаций и состояние их опроса
+CREATE TABLE IF NOT EXISTS sources (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    poll_interval INT NOT NULL DEFAULT 5,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    etag TEXT,                        -- валидаторы для условных запросов к ленте
+    last_modified TEXT,
+    last_status INT,
+    last_error TEXT,
+    last_fetched_at TIMESTAMP,
+    last_success_at TIMESTAMP,
+    consecutive_failures INT NOT NULL DEFAULT 0,
+    next_poll_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE news
+    ADD COLUMN IF NOT EXISTS source_id INT REFERENCES sources(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS url TEXT,               -- канонический URL публикации
+    ADD COLUMN IF NOT EXISTS guid TEXT,              -- GUID/ID записи в ленте
+    ADD COLUMN IF NOT EXISTS fingerprint CHAR(64);   -- sha256 от заголовка и текста
+
+CREATE UNIQUE INDEX IF NOT EXISTS news_url_key ON news (url);
+CREATE INDEX IF NOT EXISTS news_guid_idx ON news (guid);
+CREATE UNIQUE INDEX IF NOT EXISTS news_fingerprint_key ON news (fingerprint);
+CREATE INDEX IF NOT EXISTS news_source_id_idx ON news (source_id);
+
+-- Все источники, в которых вышла публикация
+CREATE TABLE IF NOT EXISTS news_sources (
+    news_id INT NOT NULL REFERENCES news(id) ON DELETE CASCADE,
+    source_id INT NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    url TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (news_id, source_id)
+);
+CREATE INDEX IF NOT EXISTS news_sources_source_id_idx ON news_sources (source_id);
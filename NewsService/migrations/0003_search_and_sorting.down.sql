@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS news_author_idx;
+DROP INDEX IF EXISTS news_comments_count_idx;
+DROP INDEX IF EXISTS news_title_idx;
+DROP INDEX IF EXISTS news_created_at_idx;
+DROP INDEX IF EXISTS news_search_idx;
+
+ALTER TABLE news
+    DROP COLUMN IF EXISTS comments_count,
+    DROP COLUMN IF EXISTS search_vector;
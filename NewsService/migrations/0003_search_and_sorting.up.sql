@@ -0,0 +1,15 @@
+-- Лексемы заголовка (вес A) и текста (вес B) на русском и английском
+ALTER TABLE news
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', title), 'A') ||
+        setweight(to_tsvector('english', title), 'A') ||
+        setweight(to_tsvector('russian', content), 'B') ||
+        setweight(to_tsvector('english', content), 'B')
+    ) STORED,
+    ADD COLUMN IF NOT EXISTS comments_count INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS news_search_idx ON news USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS news_created_at_idx ON news (created_at, id);
+CREATE INDEX IF NOT EXISTS news_title_idx ON news (title, id);
+CREATE INDEX IF NOT EXISTS news_comments_count_idx ON news (comments_count, id);
+CREATE INDEX IF NOT EXISTS news_author_idx ON news (lower(author));
@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS news_guid_idx ON news (guid);
+DROP INDEX IF EXISTS news_sources_source_guid_key;
+
+ALTER TABLE news_sources DROP COLUMN IF EXISTS guid;
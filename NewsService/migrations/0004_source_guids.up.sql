@@ -0,0 +1,10 @@
+-- GUID уникален только в пределах одной ленты, поэтому хранится для каждого
+-- источника публикации, а повторы ищутся по паре (source_id, guid)
+ALTER TABLE news_sources ADD COLUMN IF NOT EXISTS guid TEXT;
+
+UPDATE news_sources ns SET guid = n.guid
+FROM news n
+WHERE ns.news_id = n.id AND ns.source_id = n.source_id AND ns.guid IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS news_sources_source_guid_key ON news_sources (source_id, guid);
+DROP INDEX IF EXISTS news_guid_idx;
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize = 15
+	maxPageSize     = 100
+)
+
+// sortSpec описывает поле, по которому можно сортировать список новостей
+type sortSpec struct {
+	expr         string // SQL-выражение ключа сортировки
+	keyType      string // тип, к которому приводится значение ключа из курсора
+	defaultOrder string
+}
+
+var newsSorts = map[string]sortSpec{
+	"created_at": {expr: "n.created_at", keyType: "timestamp", defaultOrder: "desc"},
+	"title":      {expr: "n.title", keyType: "text", defaultOrder: "asc"},
+	"relevance":  {expr: "ts_rank(n.search_vector, q)", keyType: "real", defaultOrder: "desc"},
+	"comments":   {expr: "n.comments_count", keyType: "int", defaultOrder: "desc"},
+}
+
+// apiError - описание ошибки в ответе {"error": {...}}
+type apiError struct {
+	Code    string `json:"code"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func invalidParam(param, message string) *apiError {
+	return &apiError{Code: "invalid_parameter", Param: param, Message: message}
+}
+
+func writeError(w http.ResponseWriter, status int, apiErr *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error *apiError `json:"error"`
+	}{apiErr})
+}
+
+// newsQuery - разобранные параметры запроса списка новостей
+type newsQuery struct {
+	Search string
+	Lang   string
+	Author string
+	From   *time.Time
+	To     *time.Time
+	Page   int
+	Cursor *newsCursor
+	Limit  int
+	Sort   string
+	Order  string
+}
+
+// parseTimeParam разбирает необязательный параметр в формате RFC 3339
+func parseTimeParam(params url.Values, name string) (*time.Time, *apiError) {
+	value := params.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, invalidParam(name, name+" must be an RFC 3339 timestamp, e.g. 2024-01-02T15:04:05Z")
+	}
+	// created_at хранится без часового пояса в UTC
+	t = t.UTC()
+	return &t, nil
+}
+
+// parseNewsQuery проверяет параметры s, lang, author, from, to, page, cursor, limit, sort и order
+func parseNewsQuery(r *http.Request, defaultLang string) (newsQuery, *apiError) {
+	params := r.URL.Query()
+	q := newsQuery{
+		Search: params.Get("s"),
+		Lang:   defaultLang,
+		Page:   1,
+		Limit:  defaultPageSize,
+	}
+
+	if lang := params.Get("lang"); lang != "" {
+		var ok bool
+		if q.Lang, ok = searchLanguage(lang); !ok {
+			return q, invalidParam("lang", "lang must be one of: ru, en")
+		}
+	}
+
+	q.Author = strings.TrimSpace(params.Get("author"))
+
+	var apiErr *apiError
+	if q.From, apiErr = parseTimeParam(params, "from"); apiErr != nil {
+		return q, apiErr
+	}
+	if q.To, apiErr = parseTimeParam(params, "to"); apiErr != nil {
+		return q, apiErr
+	}
+	if q.From != nil && q.To != nil && q.From.After(*q.To) {
+		return q, invalidParam("from", "from must not be later than to")
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > maxPageSize {
+			return q, invalidParam("limit", fmt.Sprintf("limit must be an integer between 1 and %d", maxPageSize))
+		}
+		q.Limit = n
+	}
+
+	q.Sort = params.Get("sort")
+	if q.Sort == "" {
+		q.Sort = "created_at"
+		if q.Search != "" {
+			q.Sort = "relevance"
+		}
+	}
+	spec, ok := newsSorts[q.Sort]
+	if !ok {
+		return q, invalidParam("sort", "sort must be one of: created_at, title, relevance, comments")
+	}
+	if q.Sort == "relevance" && q.Search == "" {
+		return q, invalidParam("sort", "sort=relevance requires a search query in s")
+	}
+
+	q.Order = params.Get("order")
+	if q.Order == "" {
+		q.Order = spec.defaultOrder
+	}
+	if q.Order != "asc" && q.Order != "desc" {
+		return q, invalidParam("order", "order must be asc or desc")
+	}
+
+	if cursor := params.Get("cursor"); cursor != "" {
+		c, err := decodeNewsCursor(cursor)
+		if err != nil {
+			return q, invalidParam("cursor", "cursor is malformed")
+		}
+		// Курсор действителен только для той сортировки, с которой он получен
+		if (params.Get("sort") != "" && c.Sort != q.Sort) || (params.Get("order") != "" && c.Order != q.Order) {
+			return q, invalidParam("cursor", "cursor does not match sort and order")
+		}
+		if c.Sort == "relevance" && q.Search == "" {
+			return q, invalidParam("cursor", "cursor requires a search query in s")
+		}
+		if c.Sort == "relevance" && c.Query != queryFingerprint(q.Lang, q.Search) {
+			return q, invalidParam("cursor", "cursor was issued for a different search query")
+		}
+		q.Sort, q.Order = c.Sort, c.Order
+		q.Cursor = &c
+	} else if page := params.Get("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n <= 0 {
+			return q, invalidParam("page", "page must be a positive integer")
+		}
+		q.Page = n
+	}
+
+	return q, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRelevanceCursorQuery(t *testing.T) {
+	cursor := newsCursor{Sort: "relevance", Order: "desc", Key: "0.5", ID: 10, Query: queryFingerprint("russian", "golang")}.Encode()
+
+	for _, tc := range []struct {
+		name   string
+		params url.Values
+		ok     bool
+	}{
+		{"same query", url.Values{"s": {"golang"}, "cursor": {cursor}}, true},
+		{"different query", url.Values{"s": {"rust"}, "cursor": {cursor}}, false},
+		{"different language", url.Values{"s": {"golang"}, "lang": {"en"}, "cursor": {cursor}}, false},
+		{"no query", url.Values{"cursor": {cursor}}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/news?"+tc.params.Encode(), nil)
+			_, apiErr := parseNewsQuery(r, "russian")
+			if tc.ok && apiErr != nil {
+				t.Fatalf("unexpected error: %s", apiErr.Message)
+			}
+			if !tc.ok && (apiErr == nil || apiErr.Param != "cursor") {
+				t.Fatalf("got %+v, want a cursor error", apiErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+)
+
+// Конфигурации полнотекстового поиска PostgreSQL, которые можно выбрать параметром lang.
+// Колонка news.search_vector содержит лексемы обоих языков, поэтому запрос на любом
+// из них использует один и тот же GIN-индекс.
+var searchLanguages = map[string]string{
+	"ru":      "russian",
+	"russian": "russian",
+	"en":      "english",
+	"english": "english",
+}
+
+const defaultSearchLanguage = "russian"
+
+// searchLanguage возвращает конфигурацию PostgreSQL для языка или false, если язык не поддерживается
+func searchLanguage(lang string) (string, bool) {
+	cfg, ok := searchLanguages[strings.ToLower(strings.TrimSpace(lang))]
+	return cfg, ok
+}
+
+// Параметры ts_headline для фрагментов с подсветкой найденных слов
+const headlineOptions = "StartSel=<b>, StopSel=</b>, MinWords=15, MaxWords=35, MaxFragments=2, FragmentDelimiter=\" ... \""
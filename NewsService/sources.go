@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Source - источник публикаций (RSS/Atom лента)
+type Source struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	URL          string    `json:"url"`
+	PollInterval int       `json:"poll_interval"` // интервал опроса в минутах
+	Enabled      bool      `json:"enabled"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SourceHealth - история опросов источника
+type SourceHealth struct {
+	SourceID            int        `json:"source_id"`
+	LastStatus          *int       `json:"last_status"` // HTTP-статус последнего ответа ленты
+	LastError           *string    `json:"last_error"`
+	LastFetchedAt       *time.Time `json:"last_fetched_at"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	NextPollAt          *time.Time `json:"next_poll_at"`
+	ETag                *string    `json:"etag"`
+	LastModified        *string    `json:"last_modified"`
+}
+
+// sourceInput - тело запросов POST и PUT /sources
+type sourceInput struct {
+	Name         string `json:"name"`
+	URL          string `json:"url"`
+	PollInterval int    `json:"poll_interval"`
+	Enabled      *bool  `json:"enabled"` // по умолчанию источник включён
+}
+
+const (
+	defaultPollInterval = 5
+	maxPollInterval     = 7 * 24 * 60 // неделя в минутах
+)
+
+func (in *sourceInput) validate() error {
+	in.Name = strings.TrimSpace(in.Name)
+	in.URL = strings.TrimSpace(in.URL)
+
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	u, err := url.Parse(in.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	if in.PollInterval < 0 || in.PollInterval > maxPollInterval {
+		return fmt.Errorf("poll_interval must be between 1 and %d minutes", maxPollInterval)
+	}
+	if in.PollInterval == 0 {
+		in.PollInterval = defaultPollInterval
+	}
+	if in.Enabled == nil {
+		enabled := true
+		in.Enabled = &enabled
+	}
+	return nil
+}
+
+// listSources возвращает все источники
+func listSources(ctx context.Context, db *pgxpool.Pool) ([]Source, error) {
+	rows, err := db.Query(ctx, `
+	SELECT id, name, url, poll_interval, enabled, created_at FROM sources
+	ORDER BY id;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := []Source{}
+	for rows.Next() {
+		var src Source
+		if err := rows.Scan(&src.ID, &src.Name, &src.URL, &src.PollInterval, &src.Enabled, &src.CreatedAt); err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+
+	return sources, rows.Err()
+}
+
+// seedSources добавляет ленты из конфигурации в таблицу sources
+func seedSources(ctx context.Context, db *pgxpool.Pool, cfg Config) error {
+	for _, feedURL := range cfg.RSS {
+		name := feedURL
+		if u, err := url.Parse(feedURL); err == nil && u.Host != "" {
+			name = u.Host
+		}
+
+		_, err := db.Exec(ctx, `
+		INSERT INTO sources (name, url, poll_interval)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (url) DO NOTHING;
+		`, name, feedURL, cfg.RequestPeriod)
+		if err != nil {
+			return fmt.Errorf("failed to add source %s: %v", feedURL, err)
+		}
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func writeSource(w http.ResponseWriter, status int, src Source) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(src); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+func (api *API) getSources(w http.ResponseWriter, r *http.Request) {
+	sources, err := listSources(r.Context(), api.db)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch sources: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(sources); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
+
+func (api *API) getSource(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["SourceID"])
+	if err != nil {
+		http.Error(w, "Invalid SourceID", http.StatusBadRequest)
+		return
+	}
+
+	var src Source
+	err = api.db.QueryRow(r.Context(), `
+	SELECT id, name, url, poll_interval, enabled, created_at FROM sources
+	WHERE id = $1;
+	`, id).Scan(&src.ID, &src.Name, &src.URL, &src.PollInterval, &src.Enabled, &src.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Source not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch source: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeSource(w, http.StatusOK, src)
+}
+
+func (api *API) addSource(w http.ResponseWriter, r *http.Request) {
+	var in sourceInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Failed to parse request body", http.StatusBadRequest)
+		return
+	}
+	if err := in.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var src Source
+	err := api.db.QueryRow(r.Context(), `
+	INSERT INTO sources (name, url, poll_interval, enabled)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, name, url, poll_interval, enabled, created_at;
+	`, in.Name, in.URL, in.PollInterval, *in.Enabled).Scan(&src.ID, &src.Name, &src.URL, &src.PollInterval, &src.Enabled, &src.CreatedAt)
+	if isUniqueViolation(err) {
+		http.Error(w, "Source with this url already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to insert source: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeSource(w, http.StatusCreated, src)
+}
+
+func (api *API) updateSource(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["SourceID"])
+	if err != nil {
+		http.Error(w, "Invalid SourceID", http.StatusBadRequest)
+		return
+	}
+
+	var in sourceInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Failed to parse request body", http.StatusBadRequest)
+		return
+	}
+	if err := in.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var src Source
+	err = api.db.QueryRow(r.Context(), `
+	UPDATE sources
+	SET name = $2, url = $3, poll_interval = $4, enabled = $5,
+		-- при смене адреса ленты сохранённые валидаторы и ошибки больше не актуальны
+		etag = CASE WHEN url = $3 THEN etag END,
+		last_modified = CASE WHEN url = $3 THEN last_modified END,
+		consecutive_failures = CASE WHEN url = $3 THEN consecutive_failures ELSE 0 END,
+		next_poll_at = CASE WHEN url = $3 THEN next_poll_at END
+	WHERE id = $1
+	RETURNING id, name, url, poll_interval, enabled, created_at;
+	`, id, in.Name, in.URL, in.PollInterval, *in.Enabled).Scan(&src.ID, &src.Name, &src.URL, &src.PollInterval, &src.Enabled, &src.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Source not found", http.StatusNotFound)
+		return
+	}
+	if isUniqueViolation(err) {
+		http.Error(w, "Source with this url already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update source: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeSource(w, http.StatusOK, src)
+}
+
+// deleteSource удаляет источник; ранее загруженные из него новости остаются без источника
+func (api *API) deleteSource(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["SourceID"])
+	if err != nil {
+		http.Error(w, "Invalid SourceID", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := api.db.Exec(r.Context(), `DELETE FROM sources WHERE id = $1;`, id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete source: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Source not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *API) getSourceHealth(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["SourceID"])
+	if err != nil {
+		http.Error(w, "Invalid SourceID", http.StatusBadRequest)
+		return
+	}
+
+	var h SourceHealth
+	err = api.db.QueryRow(r.Context(), `
+	SELECT id, last_status, last_error, last_fetched_at, last_success_at,
+		consecutive_failures, next_poll_at, etag, last_modified
+	FROM sources
+	WHERE id = $1;
+	`, id).Scan(&h.SourceID, &h.LastStatus, &h.LastError, &h.LastFetchedAt, &h.LastSuccessAt,
+		&h.ConsecutiveFailures, &h.NextPollAt, &h.ETag, &h.LastModified)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Source not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch source health: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(h); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+	}
+}
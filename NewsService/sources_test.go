@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSourceInputPollInterval(t *testing.T) {
+	for _, tc := range []struct {
+		interval int
+		want     int // 0 - ошибка проверки
+	}{
+		{0, defaultPollInterval},
+		{15, 15},
+		{maxPollInterval, maxPollInterval},
+		{-1, 0},
+		{maxPollInterval + 1, 0},
+		// Такой интервал переполнял time.Duration, и лента опрашивалась каждую минуту
+		{1 << 40, 0},
+	} {
+		in := sourceInput{Name: "Лента", URL: "https://example.com/rss", PollInterval: tc.interval}
+		err := in.validate()
+		if tc.want == 0 {
+			if err == nil {
+				t.Errorf("poll_interval %d: want error", tc.interval)
+			}
+			continue
+		}
+		if err != nil || in.PollInterval != tc.want {
+			t.Errorf("poll_interval %d: got %d, %v, want %d", tc.interval, in.PollInterval, err, tc.want)
+		}
+	}
+}
+
+func TestSourcesRequireAdminToken(t *testing.T) {
+	requests := []struct{ method, path string }{
+		{http.MethodPost, "/sources"},
+		{http.MethodPut, "/sources/1"},
+		{http.MethodDelete, "/sources/1"},
+	}
+	for _, tc := range []struct {
+		name, token, auth string
+	}{
+		{"no header", "secret", ""},
+		{"wrong token", "secret", "Bearer other"},
+		{"token not configured", "", "Bearer "},
+	} {
+		// Запрос отклоняется до обращения к БД
+		api := NewAPI(nil, Config{AdminToken: tc.token})
+		for _, req := range requests {
+			r := httptest.NewRequest(req.method, req.path, strings.NewReader(`{"name": "Лента", "url": "https://example.com/rss"}`))
+			if tc.auth != "" {
+				r.Header.Set("Authorization", tc.auth)
+			}
+			rec := httptest.NewRecorder()
+			api.Router().ServeHTTP(rec, r)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("%s: %s %s = %d, want 403", tc.name, req.method, req.path, rec.Code)
+			}
+		}
+	}
+}
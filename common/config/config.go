@@ -0,0 +1,198 @@
+// Пакет config содержит общие для сервисов части загрузки конфигурации:
+// чтение файла, переопределение настроек переменными окружения и проверки.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration - time.Duration, которая в JSON и YAML записывается строкой вида "5s"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.New(`duration must be a string like "5s"`)
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Load читает файл конфигурации в cfg. Путь берётся из переменной окружения envVar,
+// а если она не задана - равен defaultPath; отсутствие файла по умолчанию не является
+// ошибкой, тогда всё задаётся переменными окружения. Файлы .json разбираются как JSON,
+// остальные как YAML. Неизвестные ключи считаются ошибкой, чтобы опечатка в имени
+// настройки не приводила к молчаливому использованию значения по умолчанию.
+func Load(envVar, defaultPath string, cfg any) error {
+	path, explicit := os.LookupEnv(envVar)
+	if !explicit {
+		path = defaultPath
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if filepath.Ext(path) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		// Пустой YAML-файл допустим: все настройки остаются по умолчанию
+		if err = dec.Decode(cfg); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return nil
+}
+
+// Server - таймауты HTTP-сервера
+type Server struct {
+	ReadTimeout  Duration `json:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout Duration `json:"write_timeout" yaml:"write_timeout"`
+	IdleTimeout  Duration `json:"idle_timeout" yaml:"idle_timeout"`
+	// Время на завершение текущих запросов при остановке сервиса
+	ShutdownTimeout Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+}
+
+// DefaultServer возвращает таймауты, общие для всех сервисов
+func DefaultServer() Server {
+	return Server{
+		ReadTimeout:     Duration(10 * time.Second),
+		WriteTimeout:    Duration(30 * time.Second),
+		IdleTimeout:     Duration(60 * time.Second),
+		ShutdownTimeout: Duration(15 * time.Second),
+	}
+}
+
+// Env возвращает для ApplyEnv переменные окружения таймаутов с префиксом сервиса,
+// например NEWS_READ_TIMEOUT
+func (s *Server) Env(prefix string) map[string]any {
+	return map[string]any{
+		prefix + "READ_TIMEOUT":     &s.ReadTimeout,
+		prefix + "WRITE_TIMEOUT":    &s.WriteTimeout,
+		prefix + "IDLE_TIMEOUT":     &s.IdleTimeout,
+		prefix + "SHUTDOWN_TIMEOUT": &s.ShutdownTimeout,
+	}
+}
+
+// Durations возвращает таймауты для Positive
+func (s Server) Durations() map[string]Duration {
+	return map[string]Duration{
+		"server.read_timeout":     s.ReadTimeout,
+		"server.write_timeout":    s.WriteTimeout,
+		"server.idle_timeout":     s.IdleTimeout,
+		"server.shutdown_timeout": s.ShutdownTimeout,
+	}
+}
+
+// ApplyEnv переопределяет настройки значениями непустых переменных окружения.
+// Ключ vars - имя переменной, значение - указатель на *string, *bool, *int32,
+// *float64 или *Duration.
+func ApplyEnv(vars ...map[string]any) error {
+	all := merge(vars)
+	for _, name := range sortedKeys(all) {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		switch dst := all[name].(type) {
+		case *string:
+			*dst = value
+		case *bool:
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: must be true or false", name)
+			}
+			*dst = v
+		case *int32:
+			v, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid %s: must be an integer", name)
+			}
+			*dst = int32(v)
+		case *float64:
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: must be a number", name)
+			}
+			*dst = v
+		case *Duration:
+			if err := dst.UnmarshalText([]byte(value)); err != nil {
+				return fmt.Errorf("invalid %s: %v", name, err)
+			}
+		default:
+			panic(fmt.Sprintf("config: unsupported type %T for %s", dst, name))
+		}
+	}
+	return nil
+}
+
+// Positive проверяет, что все таймауты и интервалы больше нуля.
+// Ключ durations - имя настройки в файле, например server.read_timeout.
+func Positive(durations ...map[string]Duration) error {
+	all := merge(durations)
+	for _, name := range sortedKeys(all) {
+		if all[name] <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+	return nil
+}
+
+// CheckURL проверяет, что raw - абсолютный адрес http или https
+func CheckURL(name, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s %q is not an absolute http(s) URL", name, raw)
+	}
+	return nil
+}
+
+func merge[V any](maps []map[string]V) map[string]V {
+	all := make(map[string]V)
+	for _, m := range maps {
+		for k, v := range m {
+			all[k] = v
+		}
+	}
+	return all
+}
+
+// sortedKeys нужен, чтобы при нескольких ошибках всегда сообщалось об одной и той же
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
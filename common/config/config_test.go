@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Listen  string   `json:"listen" yaml:"listen"`
+	Timeout Duration `json:"timeout" yaml:"timeout"`
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"config.json": `{"listen": ":8081", "timeout": "5s"}`,
+		"config.yaml": "listen: \":8081\"\ntimeout: 5s\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("TEST_CONFIG", path)
+
+		var cfg testConfig
+		if err := Load("TEST_CONFIG", "", &cfg); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if cfg.Listen != ":8081" || time.Duration(cfg.Timeout) != 5*time.Second {
+			t.Errorf("%s: got %+v", name, cfg)
+		}
+	}
+
+	// Опечатка в имени настройки - ошибка, а не значение по умолчанию
+	for name, data := range map[string]string{
+		"typo.json": `{"listen": ":8081", "timout": "5s"}`,
+		"typo.yaml": "listen: \":8081\"\ntimout: 5s\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("TEST_CONFIG", path)
+		if err := Load("TEST_CONFIG", "", &testConfig{}); err == nil {
+			t.Errorf("%s: want error for unknown key", name)
+		}
+	}
+
+	// Пустой YAML-файл оставляет значения по умолчанию
+	empty := filepath.Join(dir, "empty.yaml")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_CONFIG", empty)
+	if err := Load("TEST_CONFIG", "", &testConfig{}); err != nil {
+		t.Errorf("empty yaml: %v", err)
+	}
+
+	// Файла по умолчанию может не быть, а явно указанный файл обязателен
+	os.Unsetenv("TEST_CONFIG")
+	if err := Load("TEST_CONFIG", filepath.Join(dir, "missing.json"), &testConfig{}); err != nil {
+		t.Errorf("missing default file: %v", err)
+	}
+	t.Setenv("TEST_CONFIG", filepath.Join(dir, "missing.json"))
+	if err := Load("TEST_CONFIG", "", &testConfig{}); err == nil {
+		t.Error("missing explicit file: want error")
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	var (
+		listen  = ":8081"
+		migrate = true
+		conns   int32
+		ratio   float64
+		timeout Duration
+	)
+	vars := map[string]any{
+		"TEST_LISTEN":  &listen,
+		"TEST_MIGRATE": &migrate,
+		"TEST_CONNS":   &conns,
+		"TEST_RATIO":   &ratio,
+		"TEST_TIMEOUT": &timeout,
+	}
+	t.Setenv("TEST_MIGRATE", "false")
+	t.Setenv("TEST_CONNS", "20")
+	t.Setenv("TEST_RATIO", "0.5")
+	t.Setenv("TEST_TIMEOUT", "1m")
+	if err := ApplyEnv(vars); err != nil {
+		t.Fatal(err)
+	}
+	if listen != ":8081" || migrate || conns != 20 || ratio != 0.5 || timeout != Duration(time.Minute) {
+		t.Errorf("got %q %v %d %v %v", listen, migrate, conns, ratio, time.Duration(timeout))
+	}
+
+	t.Setenv("TEST_CONNS", "many")
+	if err := ApplyEnv(vars); err == nil || err.Error() != "invalid TEST_CONNS: must be an integer" {
+		t.Errorf("got %v, want an error about TEST_CONNS", err)
+	}
+}
@@ -0,0 +1,59 @@
+// Пакет logging настраивает журнал сервисов: JSON в stdout через log/slog
+// с идентификатором запроса и трассы в каждой записи.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"common/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextHandler добавляет к записям журнала идентификатор запроса и трассы из контекста
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if requestID := requestid.FromContext(ctx); requestID != "" {
+		rec.AddAttrs(slog.String("request_id", requestID))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		rec.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// ParseLevel разбирает уровень журнала: debug, info, warn или error
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(level))
+	return l, err
+}
+
+// Setup делает логгером по умолчанию JSON-логгер с заданным уровнем и полем
+// service; сообщения пакета log тоже попадают в него
+func Setup(level, service string) {
+	l, err := ParseLevel(level)
+	if err != nil {
+		l = slog.LevelInfo
+	}
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: l})
+	slog.SetDefault(slog.New(contextHandler{handler}).With("service", service))
+}
+
+// Fatal записывает ошибку в журнал и завершает процесс
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
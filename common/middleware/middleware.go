@@ -0,0 +1,99 @@
+// Пакет middleware - общий для сервисов обработчик входящих запросов:
+// идентификатор запроса, журнал доступа и метрики HTTP.
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"common/requestid"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Метрики HTTP-запросов, отдаются на /metrics
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests by route template and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route template and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Метка route для запросов, не подошедших ни к одному маршруту. Путь таких
+// запросов в метки не попадает: иначе любой сканер создавал бы новые серии.
+const unmatchedRoute = "unmatched"
+
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	bytes      int // размер тела ответа
+}
+
+// WriteHeader записывает HTTP-статус и сохраняет его в переменную
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	rw.statusCode = statusCode
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write записывает тело ответа и подсчитывает записанные байты
+func (rw *responseWriter) Write(p []byte) (n int, err error) {
+	n, err = rw.ResponseWriter.Write(p)
+	rw.bytes += n
+	return n, err
+}
+
+// Headers передаёт обработчику идентификатор запроса в контексте, возвращает его
+// клиенту в X-Request-ID, пишет запрос в журнал и учитывает его в метриках
+func Headers(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := requestid.FromRequest(r)
+		w.Header().Set(requestid.Header, requestID)
+		r = r.WithContext(requestid.NewContext(r.Context(), requestID))
+
+		// Статус 200 записывается неявно, если обработчик не вызвал WriteHeader
+		wrappedWriter := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(wrappedWriter, r)
+
+		// В журнал и метрики пишется шаблон маршрута, а не путь, чтобы записи можно было группировать
+		route := unmatchedRoute
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		latency := time.Since(start)
+		code := strconv.Itoa(wrappedWriter.statusCode)
+		httpRequests.WithLabelValues(r.Method, route, code).Inc()
+		httpRequestDuration.WithLabelValues(r.Method, route, code).Observe(latency.Seconds())
+		slog.InfoContext(r.Context(), "Request",
+			"method", r.Method,
+			"route", route,
+			"status", wrappedWriter.statusCode,
+			"latency_ms", float64(latency.Microseconds())/1000,
+			"bytes", wrappedWriter.bytes,
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
+
+// Use подключает к router middleware сервиса и Headers. Middleware маршрутизатора
+// не вызываются для ответов 404 и 405, поэтому для них Headers подключается
+// отдельно, с меткой route "unmatched".
+func Use(router *mux.Router, mwf ...mux.MiddlewareFunc) {
+	router.Use(append(mwf, Headers)...)
+	router.NotFoundHandler = Headers(http.NotFoundHandler())
+	router.MethodNotAllowedHandler = Headers(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+}
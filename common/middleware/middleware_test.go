@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"common/requestid"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRouteLabels(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/news/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if requestid.FromContext(r.Context()) == "" {
+			t.Error("handler got no request ID in context")
+		}
+	}).Methods(http.MethodGet)
+	Use(router)
+
+	for _, tc := range []struct {
+		method, path, route string
+		status              int
+	}{
+		{http.MethodGet, "/news/1", "/news/{id}", http.StatusOK},
+		{http.MethodGet, "/news/2", "/news/{id}", http.StatusOK},
+		{http.MethodGet, "/wp-admin/install.php", unmatchedRoute, http.StatusNotFound},
+		{http.MethodDelete, "/news/1", unmatchedRoute, http.StatusMethodNotAllowed},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: status %d, want %d", tc.method, tc.path, rec.Code, tc.status)
+		}
+		if rec.Header().Get(requestid.Header) == "" {
+			t.Errorf("%s %s: no %s in response", tc.method, tc.path, requestid.Header)
+		}
+	}
+
+	if n := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/news/{id}", "200")); n != 2 {
+		t.Errorf("GET /news/{id} 200: counted %v requests, want 2", n)
+	}
+	if n := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")); n != 1 {
+		t.Errorf("GET unmatched 404: counted %v requests, want 1", n)
+	}
+	if n := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodDelete, unmatchedRoute, "405")); n != 1 {
+		t.Errorf("DELETE unmatched 405: counted %v requests, want 1", n)
+	}
+	// Путь несовпавшего запроса не становится меткой
+	if n := testutil.CollectAndCount(httpRequests); n != 3 {
+		t.Errorf("http_requests_total has %d series, want 3", n)
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireToken(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	for _, tc := range []struct {
+		name, token, header string
+		want                int
+	}{
+		{"valid token", "secret", "Bearer secret", http.StatusOK},
+		{"wrong token", "secret", "Bearer guess", http.StatusForbidden},
+		{"token prefix", "secret", "Bearer secre", http.StatusForbidden},
+		{"no header", "secret", "", http.StatusForbidden},
+		{"not bearer", "secret", "Basic secret", http.StatusForbidden},
+		{"token not configured", "", "Bearer ", http.StatusForbidden},
+		{"token not configured, no header", "", "", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		RequireToken(tc.token, ok)(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Пакет migrate - миграции схемы PostgreSQL, встроенные в бинарный файл сервиса.
+// Файлы migrations/NNNN_name.up.sql и migrations/NNNN_name.down.sql применяются
+// по возрастанию версии, применённые версии записываются в таблицу schema_migrations.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Каталог миграций внутри files
+const dir = "migrations"
+
+// Migrator применяет и откатывает миграции одного сервиса
+type Migrator struct {
+	files   fs.FS // обычно embed.FS с каталогом migrations
+	lockKey int64 // ключ pg_advisory_lock, уникальный для сервиса
+}
+
+// New возвращает Migrator для миграций из каталога migrations в files. Блокировка
+// с ключом lockKey не даёт нескольким экземплярам сервиса применять миграции одновременно.
+func New(files fs.FS, lockKey int64) *Migrator {
+	return &Migrator{files: files, lockKey: lockKey}
+}
+
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// load читает миграции, упорядоченные по версии
+func (m *Migrator) load() ([]migration, error) {
+	entries, err := fs.ReadDir(m.files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		prefix, title, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s", name)
+		}
+
+		data, err := fs.ReadFile(m.files, dir+"/"+name)
+		if err != nil {
+			return nil, err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &migration{Version: version, Name: title}
+			byVersion[version] = mig
+		}
+		if direction == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down files", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// withLock создаёт schema_migrations и выполняет fn на соединении,
+// удерживающем блокировку миграций
+func (m *Migrator) withLock(ctx context.Context, db *pgxpool.Pool, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1);`, m.lockKey); err != nil {
+		return fmt.Errorf("failed to lock migrations: %v", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1);`, m.lockKey)
+
+	_, err = conn.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+
+	return fn(conn)
+}
+
+// applied возвращает время применения каждой версии
+func applied(ctx context.Context, conn *pgxpool.Conn) (map[int]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+// run выполняет SQL миграции и изменяет schema_migrations в одной транзакции
+func run(ctx context.Context, conn *pgxpool.Conn, script, record string, args ...interface{}) error {
+	return conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, script); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, record, args...)
+		return err
+	})
+}
+
+// Up применяет все ещё не применённые миграции
+func (m *Migrator) Up(ctx context.Context, db *pgxpool.Pool) error {
+	migrations, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	return m.withLock(ctx, db, func(conn *pgxpool.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			err := run(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`, mig.Version, mig.Name)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %v", mig.Version, mig.Name, err)
+			}
+			slog.Info("Applied migration", "version", mig.Version, "name", mig.Name)
+		}
+		return nil
+	})
+}
+
+// Down откатывает steps последних применённых миграций
+func (m *Migrator) Down(ctx context.Context, db *pgxpool.Pool, steps int) error {
+	migrations, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	return m.withLock(ctx, db, func(conn *pgxpool.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			mig := migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			err := run(ctx, conn, mig.Down,
+				`DELETE FROM schema_migrations WHERE version = $1;`, mig.Version)
+			if err != nil {
+				return fmt.Errorf("rollback of migration %04d_%s failed: %v", mig.Version, mig.Name, err)
+			}
+			slog.Info("Rolled back migration", "version", mig.Version, "name", mig.Name)
+			steps--
+		}
+		return nil
+	})
+}
+
+// Status выводит в out список миграций и время их применения
+func (m *Migrator) Status(ctx context.Context, db *pgxpool.Pool, out io.Writer) error {
+	migrations, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	return m.withLock(ctx, db, func(conn *pgxpool.Conn) error {
+		done, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, mig := range migrations {
+			status := "pending"
+			if appliedAt, ok := done[mig.Version]; ok {
+				status = appliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", mig.Version, mig.Name, status)
+		}
+		return w.Flush()
+	})
+}
+
+// Run выполняет подкоманду migrate up|down [N]|status; status пишется в stdout
+func (m *Migrator) Run(ctx context.Context, db *pgxpool.Pool, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down [N]|status")
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx, db)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return errors.New("migrate down: N must be a positive integer")
+			}
+			steps = n
+		}
+		return m.Down(ctx, db, steps)
+	case "status":
+		return m.Status(ctx, db, os.Stdout)
+	default:
+		return fmt.Errorf("unknown migrate command %q; usage: migrate up|down [N]|status", args[0])
+	}
+}
@@ -0,0 +1,68 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	files := fstest.MapFS{
+		"migrations/0002_add_index.up.sql":   {Data: []byte("CREATE INDEX i ON t (x);")},
+		"migrations/0002_add_index.down.sql": {Data: []byte("DROP INDEX i;")},
+		"migrations/0001_init.up.sql":        {Data: []byte("CREATE TABLE t (x INT);")},
+		"migrations/0001_init.down.sql":      {Data: []byte("DROP TABLE t;")},
+	}
+	migrations, err := New(files, 1).load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("got %d migrations, want 2", len(migrations))
+	}
+	first, second := migrations[0], migrations[1]
+	if first.Version != 1 || first.Name != "init" || first.Up != "CREATE TABLE t (x INT);" || first.Down != "DROP TABLE t;" {
+		t.Errorf("first migration = %+v", first)
+	}
+	if second.Version != 2 || second.Name != "add_index" {
+		t.Errorf("second migration = %+v", second)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down": {
+			"migrations/0001_init.up.sql": {Data: []byte("CREATE TABLE t (x INT);")},
+		},
+		"no version": {
+			"migrations/init.up.sql":   {Data: []byte("CREATE TABLE t (x INT);")},
+			"migrations/init.down.sql": {Data: []byte("DROP TABLE t;")},
+		},
+		"unknown direction": {
+			"migrations/0001_init.sideways.sql": {Data: []byte("SELECT 1;")},
+		},
+		"no migrations dir": {
+			"0001_init.up.sql": {Data: []byte("CREATE TABLE t (x INT);")},
+		},
+	}
+	for name, files := range tests {
+		if _, err := New(files, 1).load(); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+// Ошибки в аргументах подкоманды обнаруживаются до обращения к БД
+func TestRunUsage(t *testing.T) {
+	m := New(fstest.MapFS{}, 1)
+	for _, args := range [][]string{
+		nil,
+		{"sideways"},
+		{"down", "0"},
+		{"down", "many"},
+	} {
+		if err := m.Run(context.Background(), nil, args); err == nil {
+			t.Errorf("Run(%q): want error", args)
+		}
+	}
+}
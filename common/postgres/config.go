@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"common/config"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Config - подключение к PostgreSQL
+type Config struct {
+	DSN            string          `json:"dsn" yaml:"dsn"`
+	MaxConns       int32           `json:"max_conns" yaml:"max_conns"`
+	MinConns       int32           `json:"min_conns" yaml:"min_conns"`
+	ConnectTimeout config.Duration `json:"connect_timeout" yaml:"connect_timeout"`
+	AutoMigrate    bool            `json:"auto_migrate" yaml:"auto_migrate"` // применять миграции при запуске
+}
+
+// DefaultConfig возвращает настройки пула по умолчанию; DSN задаётся сервисом
+func DefaultConfig() Config {
+	return Config{
+		MaxConns:       10,
+		ConnectTimeout: config.Duration(5 * time.Second),
+		AutoMigrate:    true,
+	}
+}
+
+// Env возвращает для config.ApplyEnv переменные окружения БД с префиксом
+// сервиса, например NEWS_DATABASE_DSN и NEWS_DB_MAX_CONNS
+func (c *Config) Env(prefix string) map[string]any {
+	return map[string]any{
+		prefix + "DATABASE_DSN":       &c.DSN,
+		prefix + "DB_MAX_CONNS":       &c.MaxConns,
+		prefix + "DB_MIN_CONNS":       &c.MinConns,
+		prefix + "DB_CONNECT_TIMEOUT": &c.ConnectTimeout,
+		prefix + "DB_AUTO_MIGRATE":    &c.AutoMigrate,
+	}
+}
+
+// Durations возвращает таймауты для проверки config.Positive
+func (c Config) Durations() map[string]config.Duration {
+	return map[string]config.Duration{
+		"database.connect_timeout": c.ConnectTimeout,
+	}
+}
+
+func (c Config) Validate() error {
+	if c.DSN == "" {
+		return errors.New("database DSN is required (database.dsn)")
+	}
+	if _, err := pgxpool.ParseConfig(c.DSN); err != nil {
+		return fmt.Errorf("invalid database DSN: %v", err)
+	}
+	if c.MaxConns <= 0 {
+		return errors.New("database.max_conns must be positive")
+	}
+	if c.MinConns < 0 || c.MinConns > c.MaxConns {
+		return errors.New("database.min_conns must be between 0 and max_conns")
+	}
+	return nil
+}
+
+// Connect создаёт пул соединений с параметрами из cfg. Если traced, запросы
+// становятся спанами трассировки. Отмена ctx прерывает подключение.
+func Connect(ctx context.Context, cfg Config, traced bool) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database DSN: %v", err)
+	}
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.ConnConfig.ConnectTimeout = time.Duration(cfg.ConnectTimeout)
+	if traced {
+		poolConfig.ConnConfig.Logger = QueryTracer{}
+		poolConfig.ConnConfig.LogLevel = pgx.LogLevelInfo
+	}
+
+	return pgxpool.ConnectConfig(ctx, poolConfig)
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Время ожидания ответа БД при проверке готовности
+const readyTimeout = 2 * time.Second
+
+// Pinger - пул соединений, который проверяет Readyz; *pgxpool.Pool подходит
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// healthStatus - ответ /healthz и /readyz
+type healthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func writeHealth(w http.ResponseWriter, status int, body healthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Healthz - проверка живости: процесс запущен и обрабатывает запросы
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthStatus{Status: "ok"})
+}
+
+// Readyz возвращает проверку готовности: пул соединений может выполнить запрос к БД
+func Readyz(db Pinger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		defer cancel()
+
+		if err := db.Ping(ctx); err != nil {
+			writeHealth(w, http.StatusServiceUnavailable, healthStatus{Status: "unavailable", Error: "database: " + err.Error()})
+			return
+		}
+		writeHealth(w, http.StatusOK, healthStatus{Status: "ok"})
+	}
+}
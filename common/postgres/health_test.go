@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type pingFunc func(ctx context.Context) error
+
+func (f pingFunc) Ping(ctx context.Context) error { return f(ctx) }
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name     string
+		ping     error
+		wantCode int
+		wantBody string
+	}{
+		{"up", nil, http.StatusOK, `{"status":"ok"}`},
+		{"down", errors.New("connection refused"), http.StatusServiceUnavailable,
+			`{"status":"unavailable","error":"database: connection refused"}`},
+	}
+	for _, tt := range tests {
+		handler := Readyz(pingFunc(func(ctx context.Context) error {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Errorf("%s: ping without deadline", tt.name)
+			}
+			return tt.ping
+		}))
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+		if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+			t.Errorf("%s: body = %s, want %s", tt.name, got, tt.wantBody)
+		}
+	}
+}
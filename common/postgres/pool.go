@@ -0,0 +1,57 @@
+// Пакет postgres - подключение к PostgreSQL, проверка готовности БД, метрики пула
+// соединений pgx и трассировка запросов
+package postgres
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector отдаёт статистику пула соединений с БД
+type PoolCollector struct {
+	db *pgxpool.Pool
+
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	maxConns        *prometheus.Desc
+	acquireCount    *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	acquireDuration *prometheus.Desc
+}
+
+func NewPoolCollector(db *pgxpool.Pool) *PoolCollector {
+	return &PoolCollector{
+		db:            db,
+		acquiredConns: prometheus.NewDesc("db_pool_acquired_conns", "Connections currently in use.", nil, nil),
+		idleConns:     prometheus.NewDesc("db_pool_idle_conns", "Idle connections in the pool.", nil, nil),
+		totalConns:    prometheus.NewDesc("db_pool_total_conns", "Total connections in the pool.", nil, nil),
+		maxConns:      prometheus.NewDesc("db_pool_max_conns", "Maximum size of the pool.", nil, nil),
+		acquireCount:  prometheus.NewDesc("db_pool_acquires_total", "Successful connection acquires.", nil, nil),
+		emptyAcquires: prometheus.NewDesc("db_pool_empty_acquires_total",
+			"Acquires that had to wait for a connection because the pool was empty.", nil, nil),
+		acquireDuration: prometheus.NewDesc("db_pool_acquire_wait_seconds_total",
+			"Total time spent waiting to acquire a connection.", nil, nil),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.emptyAcquires
+	ch <- c.acquireDuration
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.db.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Имя, под которым создаются спаны запросов; сервис указывается в ресурсе трассировки
+const tracerName = "common/postgres"
+
+// QueryTracer создаёт спаны запросов к PostgreSQL по событиям журнала pgx.
+// pgx v4 сообщает о запросе после его выполнения, поэтому начало спана
+// вычисляется по длительности запроса.
+type QueryTracer struct{}
+
+func (QueryTracer) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	elapsed, ok := data["time"].(time.Duration)
+	if !ok {
+		// Служебные события (подключение, закрытие соединения) не являются запросами
+		return
+	}
+	end := time.Now()
+
+	attrs := []attribute.KeyValue{semconv.DBSystemPostgreSQL}
+	if sql, ok := data["sql"].(string); ok {
+		attrs = append(attrs, semconv.DBStatement(sql))
+	}
+	switch rows := data["rowCount"].(type) {
+	case int:
+		attrs = append(attrs, attribute.Int("db.rows", rows))
+	case int64:
+		attrs = append(attrs, attribute.Int64("db.rows", rows))
+	}
+
+	_, span := otel.Tracer(tracerName).Start(ctx, "db "+msg,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(end.Add(-elapsed)),
+		trace.WithAttributes(attrs...),
+	)
+	if err, ok := data["err"].(error); ok {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
@@ -0,0 +1,66 @@
+// Пакет requestid - идентификатор запроса, по которому связываются записи
+// журнала всех сервисов, обработавших один запрос клиента.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Header - заголовок с идентификатором запроса. Сервисы возвращают его в ответе
+// и передают в своих запросах к другим сервисам.
+const Header = "X-Request-ID"
+
+type key struct{}
+
+// New создаёт новый идентификатор
+func New() string {
+	return uuid.NewString()
+}
+
+// NewContext возвращает копию ctx с идентификатором запроса
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, key{}, requestID)
+}
+
+// FromContext возвращает идентификатор запроса, сохранённый NewContext
+func FromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(key{}).(string)
+	return requestID
+}
+
+// FromRequest берёт идентификатор из заголовка X-Request-ID или, для старых клиентов,
+// из параметра request_id. Если его нет или он недопустим, создаётся новый.
+func FromRequest(r *http.Request) string {
+	requestID := r.Header.Get(Header)
+	if requestID == "" {
+		requestID = r.URL.Query().Get("request_id")
+	}
+	if !Valid(requestID) {
+		requestID = New()
+	}
+	return requestID
+}
+
+// Valid отсекает слишком длинные идентификаторы и управляющие символы,
+// чтобы значение из заголовка можно было безопасно писать в журнал
+func Valid(requestID string) bool {
+	if requestID == "" || len(requestID) > 128 {
+		return false
+	}
+	for _, c := range requestID {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Set передаёт идентификатор запроса из контекста req в заголовке исходящего запроса
+func Set(req *http.Request) {
+	if requestID := FromContext(req.Context()); requestID != "" {
+		req.Header.Set(Header, requestID)
+	}
+}
@@ -0,0 +1,36 @@
+package requestid
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFromRequest(t *testing.T) {
+	for _, tc := range []struct {
+		name, header, query string
+		want                string // пусто - ожидается новый идентификатор
+	}{
+		{name: "header", header: "abc-123", want: "abc-123"},
+		{name: "query", query: "legacy-1", want: "legacy-1"},
+		{name: "header wins", header: "abc-123", query: "legacy-1", want: "abc-123"},
+		{name: "control characters", header: "abc\x1b[31m"},
+		{name: "spaces", header: "abc 123"},
+		{name: "too long", header: strings.Repeat("a", 129)},
+		{name: "missing"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/?request_id="+tc.query, nil)
+			if tc.header != "" {
+				r.Header.Set(Header, tc.header)
+			}
+			got := FromRequest(r)
+			if tc.want != "" && got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if tc.want == "" && (got == tc.header || !Valid(got)) {
+				t.Errorf("got %q, want a new valid ID", got)
+			}
+		})
+	}
+}
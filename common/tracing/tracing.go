@@ -0,0 +1,101 @@
+// Пакет tracing - настройка трассировки OpenTelemetry, общая для сервисов
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"common/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// Config - экспорт трассировки OpenTelemetry
+type Config struct {
+	Exporter    string  `json:"exporter" yaml:"exporter"`         // none, stdout или otlp
+	Endpoint    string  `json:"endpoint" yaml:"endpoint"`         // адрес OTLP/HTTP-коллектора; по умолчанию из OTEL_EXPORTER_OTLP_ENDPOINT
+	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio"` // доля трассируемых запросов от 0 до 1
+}
+
+// DefaultConfig возвращает конфигурацию, при которой спаны не экспортируются
+func DefaultConfig() Config {
+	return Config{Exporter: "none", SampleRatio: 1}
+}
+
+// Env возвращает для config.ApplyEnv переменные окружения трассировки с префиксом
+// сервиса, например NEWS_TRACING_EXPORTER
+func (c *Config) Env(prefix string) map[string]any {
+	return map[string]any{
+		prefix + "TRACING_EXPORTER":     &c.Exporter,
+		prefix + "TRACING_ENDPOINT":     &c.Endpoint,
+		prefix + "TRACING_SAMPLE_RATIO": &c.SampleRatio,
+	}
+}
+
+// Enabled сообщает, экспортируются ли спаны
+func (c Config) Enabled() bool {
+	return c.Exporter != "none"
+}
+
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		return fmt.Errorf("invalid tracing.exporter %q: must be none, stdout or otlp", c.Exporter)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return errors.New("tracing.sample_ratio must be between 0 and 1")
+	}
+	if c.Endpoint != "" {
+		return config.CheckURL("tracing.endpoint", c.Endpoint)
+	}
+	return nil
+}
+
+// Setup настраивает экспорт спанов и передачу контекста трассировки в заголовках
+// W3C traceparent и tracestate. Возвращённая функция отправляет накопленные спаны
+// при остановке сервиса.
+func Setup(ctx context.Context, cfg Config, service string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "stdout":
+		// В stdout пишется журнал в JSON, спаны выводятся в stderr, чтобы не смешиваться с ним
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		// Спаны не записываются, но входящий traceparent передаётся дальше
+		return func(context.Context) error { return nil }, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(service)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Решение о записи трассы принимает первый сервис в цепочке и передаёт его в traceparent
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}